@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gogen/internal/config"
+)
+
+// archiveTime is the modification time of archive entries, fixed so that
+// the same outputs always make the same archive. It is the earliest time
+// zip can represent.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// archive bundles generated outputs into a single stream.
+type archive interface {
+	add(name string, data []byte) error
+	Close() error
+}
+
+// newArchive creates an archive of the given format ("tar" or "zip")
+// writing to w.
+func newArchive(format string, w io.Writer) (archive, error) {
+	switch format {
+	case "tar":
+		return &tarArchive{w: tar.NewWriter(w)}, nil
+	case "zip":
+		return &zipArchive{w: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown archive format %q (want tar or zip)", format)
+}
+
+// tarArchive writes outputs to a tar stream.
+type tarArchive struct {
+	w *tar.Writer
+}
+
+func (a *tarArchive) add(name string, data []byte) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  archiveTime,
+		Format:   tar.FormatPAX,
+	}
+	if err := a.w.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if _, err := a.w.Write(data); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
+}
+
+func (a *tarArchive) Close() error {
+	return a.w.Close()
+}
+
+// zipArchive writes outputs to a zip stream.
+type zipArchive struct {
+	w *zip.Writer
+}
+
+func (a *zipArchive) add(name string, data []byte) error {
+	hdr := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: archiveTime,
+	}
+	hdr.SetMode(0o644)
+	f, err := a.w.CreateHeader(hdr)
+	if err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
+}
+
+func (a *zipArchive) Close() error {
+	return a.w.Close()
+}
+
+// generateArchive generates the targets into an archive on stdout, with
+// one entry per output file. Nothing is written to disk, and the cache is
+// not used. Targets are rendered concurrently, and added in order.
+func (o *options) generateArchive(cfg *config.Config, targets []config.Target) error {
+	a, err := newArchive(o.archive, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(targets))
+	seen := make(map[string]bool, len(targets))
+	for i, t := range targets {
+		if names[i], err = archiveName(t.Output); err != nil {
+			return targetError(t, err)
+		}
+		if seen[names[i]] {
+			return targetError(t, fmt.Errorf("archive entry %s is written by several targets", names[i]))
+		}
+		seen[names[i]] = true
+	}
+
+	results := o.runTargets(targets, func(t config.Target, r *targetResult) error {
+		out, err := o.render(targetConfig(cfg, t), t, &r.log)
+		r.out = out
+		return err
+	})
+	if err := flushResults(targets, results); err != nil {
+		return err
+	}
+	for i, r := range results {
+		if err := a.add(names[i], r.out); err != nil {
+			return err
+		}
+		if o.verbose {
+			fmt.Fprintf(os.Stderr, "Added %s to archive\n", names[i])
+		}
+	}
+	return a.Close()
+}
+
+// archiveName returns the archive entry name of an output file: its path
+// relative to the working directory, with forward slashes.
+func archiveName(output string) (string, error) {
+	if output == "" {
+		return "", fmt.Errorf("output file is required to name the archive entry")
+	}
+	name := path.Clean(filepath.ToSlash(output))
+	if filepath.IsAbs(output) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("output %s is outside the working directory and cannot be archived", output)
+	}
+	return name, nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"gogen/internal/cache"
+	"gogen/internal/config"
+	"gogen/internal/generator"
+	"gogen/templates"
+)
+
+// runGenerate generates the targets given by flags or the config file.
+func runGenerate(fs *flag.FlagSet, args []string) error {
+	o := newOptions()
+	o.generateFlags(fs)
+	fs.StringVar(&o.archive, "archive", "", "Write all outputs to a tar or zip archive on stdout")
+	if err := o.parseFlags(fs, args); err != nil {
+		return err
+	}
+	return o.report(o.generate(fs.Args()))
+}
+
+// generate generates the targets named (or all of them).
+func (o *options) generate(names []string) error {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	targets, err := o.targets(cfg, names)
+	if err != nil {
+		return err
+	}
+	if o.archive != "" {
+		return o.generateArchive(cfg, targets)
+	}
+	results := o.runTargets(targets, func(t config.Target, r *targetResult) error {
+		return o.generateTarget(targetConfig(cfg, t), t, r)
+	})
+	err = flushResults(targets, results)
+	if writesFiles(targets) {
+		// Once, after all targets have assigned their numbers
+		if saveErr := o.locks.Save(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// writesFiles reports whether any target writes an output file, rather than
+// printing its output, in which case nothing is written to disk.
+func writesFiles(targets []config.Target) bool {
+	for _, t := range targets {
+		if t.Output != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// runCheck regenerates the targets in memory and reports the outputs that
+// differ from the files on disk.
+func runCheck(fs *flag.FlagSet, args []string) error {
+	o := newOptions()
+	o.generateFlags(fs)
+	if err := o.parseFlags(fs, args); err != nil {
+		return err
+	}
+	return o.report(o.check(fs.Args()))
+}
+
+// check checks the outputs of the targets named (or all of them).
+func (o *options) check(names []string) error {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	targets, err := o.targets(cfg, names)
+	if err != nil {
+		return err
+	}
+
+	results := o.runTargets(targets, func(t config.Target, r *targetResult) error {
+		if t.Output == "" {
+			return fmt.Errorf("output file is required to check a target")
+		}
+		out, err := o.render(targetConfig(cfg, t), t, &r.log)
+		if err != nil {
+			return err
+		}
+		existing, err := os.ReadFile(t.Output)
+		if err != nil || !bytes.Equal(withoutCommand(existing), withoutCommand(out)) {
+			r.stale = true
+			fmt.Fprintf(&r.log, "%s is out of date\n", t.Output)
+		} else if o.verbose {
+			fmt.Fprintf(&r.log, "%s is up to date\n", t.Output)
+		}
+		return nil
+	})
+	if err := flushResults(targets, results); err != nil {
+		return err
+	}
+
+	stale := 0
+	for _, r := range results {
+		if r.stale {
+			stale++
+		}
+	}
+	if stale > 0 {
+		return fmt.Errorf("%d generated file(s) out of date, run gogen generate", stale)
+	}
+	return nil
+}
+
+// withoutCommand returns an output without the command line recorded in its
+// header, which check ignores: an output generated with a different but
+// equivalent command (e.g., by an older gogen) is up to date.
+func withoutCommand(src []byte) []byte {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	for i, line := range lines {
+		if i == headerLines {
+			break
+		}
+		if bytes.HasPrefix(bytes.TrimLeft(line, "/#-* \t"), []byte("Command: ")) {
+			return bytes.Join(append(lines[:i:i], lines[i+1:]...), nil)
+		}
+	}
+	return src
+}
+
+// headerLines is the number of lines at the start of an output searched
+// for its command line.
+const headerLines = 10
+
+// targetError adds the target name (if any) to an error.
+func targetError(t config.Target, err error) error {
+	if t.Name == "" {
+		return err
+	}
+	return fmt.Errorf("target %s: %w", t.Name, err)
+}
+
+// generateTarget generates one target, skipping it when the cache shows
+// nothing it depends on has changed, and writing the output only if its
+// content changed.
+func (o *options) generateTarget(cfg *config.Config, t config.Target, r *targetResult) error {
+	var genCache *cache.Cache
+	var cacheKey string
+	if t.Output != "" && o.useCache() {
+		var err error
+		genCache, cacheKey, err = o.openCache(cfg, t)
+		if err != nil {
+			return err
+		}
+		if genCache != nil && genCache.Fresh(t.Output, cacheKey) {
+			if o.verbose {
+				fmt.Fprintf(&r.log, "%s is up to date\n", t.Output)
+			}
+			return nil
+		}
+	}
+
+	out, err := o.render(cfg, t, &r.log)
+	if err != nil {
+		return err
+	}
+
+	if t.Output == "" {
+		r.stdout.Write(out)
+		return nil
+	}
+	written, err := cache.WriteFile(t.Output, out)
+	if err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	if genCache != nil {
+		if err := genCache.Store(t.Output, cacheKey, out); err != nil {
+			return err
+		}
+	}
+
+	if o.verbose {
+		if written {
+			fmt.Fprintf(&r.log, "Generated output to %s\n", t.Output)
+		} else {
+			fmt.Fprintf(&r.log, "%s is unchanged\n", t.Output)
+		}
+	}
+	return nil
+}
+
+// useCache reports whether targets may be skipped through the cache. A
+// skipped target reports no diagnostics, so the cache is not used when
+// they decide the result (--strict) or are read by tools (--format json).
+func (o *options) useCache() bool {
+	return !o.noCache && !o.strict && o.format != "json"
+}
+
+// render parses the input of a target and renders its template in memory:
+// the existing output is read for its protected regions, and is left
+// untouched if generation fails. Verbose messages are written to log. Proto
+// numbers are assigned in the shared lock files, which only generate saves.
+func (o *options) render(cfg *config.Config, t config.Target, log io.Writer) ([]byte, error) {
+	file, err := o.parseInput(cfg, t.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.verbose {
+		fmt.Fprintf(log, "Parsed %d types from %s\n", len(file.Types), t.Input)
+		for _, typ := range file.Types {
+			fmt.Fprintf(log, "  - %s (%s%s)\n", typ.Name, typ.Kind, marshalNote(typ))
+		}
+	}
+
+	gen := generator.New(cfg)
+	gen.SetOutput(t.Output)
+	gen.SetCommand(o.commandLine(t))
+	gen.SetDiagnostics(o.diags)
+	gen.SetProtoLocks(o.locks)
+	if err := gen.LoadTemplate(t.Template); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// openCache opens the generation cache and computes the cache key of a
+// target from its input, template, config, command line and the gogen
+// version. It returns a nil cache when no cache directory is available.
+func (o *options) openCache(cfg *config.Config, t config.Target) (*cache.Cache, string, error) {
+	dir := o.cacheDir
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, "", nil
+		}
+		dir = filepath.Join(userDir, "gogen")
+	}
+	c, err := cache.Open(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	input, err := o.inputSource(t.Input, t.Output)
+	if err != nil {
+		return nil, "", err
+	}
+	tmpl, err := templateSource(t.Template)
+	if err != nil {
+		return nil, "", err
+	}
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, "", fmt.Errorf("encoding config: %w", err)
+	}
+	var lock []byte
+	if cfg.Options.ProtoLock != "" {
+		// Field numbers come from the lock file, which may be edited by hand
+		lock, _ = os.ReadFile(cfg.Options.ProtoLock)
+	}
+
+	key := cache.Key([]byte(buildVersion()), input, tmpl, cfgJSON, lock, []byte(t.Output), []byte(o.commandLine(t)))
+	return c, key, nil
+}
+
+// inputSource returns the content of an input file or stdin, or of the Go
+// files of an input package directory. Test files and the output of the
+// target, which the parser skips, are left out of a package, so that
+// writing the output does not invalidate the cache.
+func (o *options) inputSource(path, output string) ([]byte, error) {
+	if path == stdinPath {
+		return o.readStdin()
+	}
+	if !isDir(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading input: %w", err)
+		}
+		return data, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("reading input: %w", err)
+	}
+	parts := make([][]byte, 0, 2*len(files))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || (output != "" && sameFile(file, output)) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading input: %w", err)
+		}
+		parts = append(parts, []byte(filepath.Base(file)), data)
+	}
+	return []byte(cache.Key(parts...)), nil
+}
+
+// sameFile reports whether two paths name the same file.
+func sameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// templateSource returns the source of a template file or built-in template.
+func templateSource(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if name, ok := templates.Lookup(path); ok {
+			return fs.ReadFile(templates.FS, name)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	return data, nil
+}
+
+// buildVersion identifies the running gogen build. Development builds
+// without a release version are identified by the hash of the executable,
+// so that changes to gogen itself invalidate the cache.
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	if exe, err := os.Executable(); err == nil {
+		if data, err := os.ReadFile(exe); err == nil {
+			return "dev-" + cache.Key(data)
+		}
+	}
+	return version
+}
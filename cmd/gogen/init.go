@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gogen/internal/config"
+	"gogen/templates"
+)
+
+// runInit writes a .gogen.yaml with one target in the working directory,
+// and copies the built-in template of the target so that it can be
+// customized.
+func runInit(fs *flag.FlagSet, args []string) error {
+	var input, tmpl, output string
+	var force bool
+	fs.StringVar(&input, "input", "models.go", "Input Go source file")
+	fs.StringVar(&input, "i", "models.go", "Input Go source file (shorthand)")
+	fs.StringVar(&tmpl, "template", "typescript", "Built-in template to start from")
+	fs.StringVar(&tmpl, "t", "typescript", "Built-in template to start from (shorthand)")
+	fs.StringVar(&output, "output", "", "Output file (default: input name with the template's extension)")
+	fs.StringVar(&output, "o", "", "Output file (shorthand)")
+	fs.BoolVar(&force, "force", false, "Overwrite existing files")
+	fs.BoolVar(&force, "f", false, "Overwrite existing files (shorthand)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	src, err := builtinTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(tmpl, ".tmpl")
+	tmplPath := filepath.Join("templates", name+".tmpl")
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + defaultExt(name)
+	}
+
+	cfg := struct {
+		Targets []config.Target `yaml:"targets"`
+	}{
+		Targets: []config.Target{{Name: name, Input: input, Template: tmplPath, Output: output}},
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	data = append([]byte("# gogen configuration, see \"gogen help generate\"\n"), data...)
+
+	if !force {
+		for _, path := range []string{config.FileName, tmplPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists (use -f to overwrite)", path)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(tmplPath), 0o755); err != nil {
+		return fmt.Errorf("creating templates directory: %w", err)
+	}
+	if err := os.WriteFile(tmplPath, src, 0o644); err != nil {
+		return fmt.Errorf("writing template: %w", err)
+	}
+	if err := os.WriteFile(config.FileName, data, 0o644); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Created %s and %s\nRun \"gogen generate\" to generate %s\n", config.FileName, tmplPath, output)
+	return nil
+}
+
+// defaultExt returns the suffix of the output file of a built-in template,
+// appended to the input name without its extension.
+func defaultExt(name string) string {
+	if strings.HasPrefix(name, "go-") {
+		return "_" + strings.TrimPrefix(name, "go-") + ".go"
+	}
+	return templates.OutputExt(name)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gogen/internal/generator"
+	"gogen/internal/model"
+)
+
+// runInspect prints the model parsed from an input file as JSON, which is
+// what templates see.
+func runInspect(fs *flag.FlagSet, args []string) error {
+	o := newOptions()
+	o.inputFlags(fs)
+	if err := o.parseFlags(fs, args); err != nil {
+		return err
+	}
+	return o.report(o.inspect())
+}
+
+// inspect prints the parsed model of the input.
+func (o *options) inspect() error {
+	file, err := o.parseFiltered()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding types: %w", err)
+	}
+	_, err = fmt.Fprintf(os.Stdout, "%s\n", data)
+	return err
+}
+
+// runListTypes lists the types of an input file with their kind and
+// position.
+func runListTypes(fs *flag.FlagSet, args []string) error {
+	o := newOptions()
+	o.inputFlags(fs)
+	if err := o.parseFlags(fs, args); err != nil {
+		return err
+	}
+	return o.report(o.listTypes())
+}
+
+// listTypes prints the types of the input.
+func (o *options) listTypes() error {
+	file, err := o.parseFiltered()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, t := range file.Types {
+		fmt.Fprintf(w, "%s\t%s\t%s", t.Name, t.Kind, t.Pos)
+		if note := marshalNote(t); note != "" {
+			fmt.Fprintf(w, "\t%s", strings.TrimPrefix(note, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// marshalNote describes how a type serializes when its marshaling methods
+// replace its declared shape (e.g., ", marshals as string: implements
+// encoding.TextMarshaler"), or returns "".
+func marshalNote(t model.Type) string {
+	shape, err := generator.MarshalShape(t)
+	switch {
+	case err != nil:
+		return ", " + err.Error()
+	case shape == "":
+		return ""
+	case t.Directives.Has("marshal"):
+		return ", marshals as " + shape + ": //gogen:marshal"
+	}
+	return ", marshals as " + shape + ": implements " + t.Marshaler
+}
+
+// parseFiltered parses the input given with -i and keeps the types
+// selected by the config and flags.
+func (o *options) parseFiltered() (*model.File, error) {
+	if o.input == "" {
+		o.input = o.goGenerateInput()
+	}
+	if o.input == "" {
+		return nil, fmt.Errorf("input file is required (-i or --input)")
+	}
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	file, err := o.parseInput(cfg, o.input)
+	if err != nil {
+		return nil, err
+	}
+
+	types := file.Types[:0]
+	for _, t := range file.Types {
+		if cfg.ShouldIncludeType(t.Name, t.IsExported) {
+			types = append(types, t)
+		}
+	}
+	file.Types = types
+	return file, nil
+}
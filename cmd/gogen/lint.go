@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gogen/internal/generator"
+)
+
+// runLintTemplate checks templates statically: field accesses against the
+// model, functions and their arity, and unused define blocks.
+func runLintTemplate(fs *flag.FlagSet, args []string) error {
+	o := newOptions()
+	fs.StringVar(&o.configFile, "config", "", "Config file (YAML/JSON, default: nearest .gogen.yaml)")
+	fs.StringVar(&o.configFile, "c", "", "Config file (shorthand)")
+	o.diagFlags(fs)
+	if err := o.parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("template file is required")
+	}
+	return o.report(o.lintTemplates(fs.Args()))
+}
+
+// lintTemplates checks template files or built-in templates.
+func (o *options) lintTemplates(paths []string) error {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	gen := generator.New(cfg)
+	for _, path := range paths {
+		diags, err := gen.LintTemplate(path)
+		if err != nil {
+			return err
+		}
+		o.diags.Add(diags...)
+	}
+	return nil
+}
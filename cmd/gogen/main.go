@@ -3,180 +3,184 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 
 	"gogen/internal/config"
-	"gogen/internal/generator"
-	"gogen/internal/parser"
 )
 
-var (
-	inputFile    string
-	templateFile string
-	configFile   string
-	outputFile   string
-	perType      bool
-	exportedOnly bool
-	tagKey       string
-	types        string
-	exclude      string
-	verbose      bool
-	showHelp     bool
-)
-
-func init() {
-	flag.StringVar(&inputFile, "input", "", "Input Go source file (required)")
-	flag.StringVar(&inputFile, "i", "", "Input Go source file (shorthand)")
-
-	flag.StringVar(&templateFile, "template", "", "Template file (required)")
-	flag.StringVar(&templateFile, "t", "", "Template file (shorthand)")
-
-	flag.StringVar(&configFile, "config", "", "Config file (YAML/JSON)")
-	flag.StringVar(&configFile, "c", "", "Config file (shorthand)")
-
-	flag.StringVar(&outputFile, "output", "", "Output file (default: stdout)")
-	flag.StringVar(&outputFile, "o", "", "Output file (shorthand)")
+// version is the gogen version, set at build time with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
 
-	flag.BoolVar(&perType, "per-type", false, "Execute template once per type")
-	flag.BoolVar(&exportedOnly, "exported", true, "Only process exported types")
-	flag.StringVar(&tagKey, "tag", "json", "Tag key for field names")
-	flag.StringVar(&types, "types", "", "Only generate for these types (comma-separated)")
-	flag.StringVar(&types, "T", "", "Only generate for these types (shorthand)")
-	flag.StringVar(&exclude, "exclude", "", "Exclude these types (comma-separated)")
-	flag.StringVar(&exclude, "X", "", "Exclude these types (shorthand)")
-	flag.BoolVar(&verbose, "v", false, "Verbose output")
-	flag.BoolVar(&showHelp, "h", false, "Show help")
-	flag.BoolVar(&showHelp, "help", false, "Show help")
+// command is a gogen subcommand.
+type command struct {
+	name    string
+	args    string // Argument synopsis shown in help
+	summary string
+	run     func(fs *flag.FlagSet, args []string) error
+}
 
-	flag.Usage = usage
+// commands lists the subcommands in the order they are shown in help.
+var commands = []command{
+	{"init", "[flags]", "Create a .gogen.yaml and a template to customize", runInit},
+	{"generate", "[flags] [target...]", "Generate code from Go types (the default command)", runGenerate},
+	{"check", "[flags] [target...]", "Check that generated files are up to date", runCheck},
+	{"inspect", "[flags]", "Print the types parsed from an input file as JSON", runInspect},
+	{"list-types", "[flags]", "List the types of an input file", runListTypes},
+	{"test", "[flags] [dir]", "Run golden-file tests of a template (dir defaults to testdata)", runTest},
+	{"lint-template", "[flags] template...", "Check templates against the model without running them", runLintTemplate},
+	{"templates", "[name]", "List the built-in templates, or print one", runTemplates},
 }
 
 func usage() {
 	fmt.Fprintf(os.Stderr, `gogen - Go type code generator
 
 Usage:
-    gogen -i <input.go> -t <template.tmpl> [options]
+    gogen <command> [flags] [arguments]
+    gogen -i <input.go> -t <template.tmpl> [flags]   (same as gogen generate)
 
-Options:
+Commands:
 `)
-	flag.PrintDefaults()
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "    %-12s %s\n", cmd.name, cmd.summary)
+	}
 	fmt.Fprintf(os.Stderr, `
+Run "gogen help <command>" for the flags of a command.
+
+Without -c, the configuration is read from the nearest %s in the
+working directory or its parents. Relative paths in the configuration are
+relative to the configuration file.
+
+Under go generate, -i defaults to the file holding the directive ($GOFILE),
+or with --package to the package in its directory ($GOPACKAGE).
+
 Examples:
+    # Scaffold a config file and a template
+    gogen init -i models.go -t typescript -o models.ts
+
+    # Generate all targets of .gogen.yaml
+    gogen generate
+
     # Generate TypeScript types
-    gogen -i models.go -t typescript.tmpl -o models.ts
+    gogen generate -i models.go -t typescript.tmpl -o models.ts
 
     # Generate schema for specific structs only
-    gogen -i models.go -t zod.tmpl -T User,Product -o schemas.ts
+    gogen generate -i models.go -t zod.tmpl -T User,Product -o schemas.ts
 
-    # Generate with multiple types (alternative syntax)
-    gogen -i models.go -t typescript.tmpl --types "User, Order, Product"
+    # Generate Pydantic models with a built-in template
+    gogen generate -i models.go -t pydantic -o models.py
 
-    # Exclude specific types
-    gogen -i models.go -t typescript.tmpl -X InternalConfig,PrivateData
+    # Generate Validate() methods next to the input (Go output is gofmt'd)
+    gogen generate -i models.go -t go-validate -o models_validate.go
 
-    # Generate with custom config
-    gogen -i models.go -t zod.tmpl -c config.yaml -o schemas.ts
+    # In a Go file: generate next to it with go generate ./...
+    //go:generate gogen -t go-validate -o models_validate.go
 
-    # Generate per-type output to stdout
-    gogen -i models.go -t typescript.tmpl --per-type
+    # Read Go source from stdin and bundle all targets into a tar stream
+    cat models.go | gogen generate -i - -t zod -o schemas.ts --archive tar > out.tar
 
-    # Only process specific tag
-    gogen -i models.go -t typescript.tmpl --tag yaml
+    # Fail in CI when generated files are stale
+    gogen check
 
-`)
+    # Test a template against testdata/<case>/{input.go,config.yaml,expected.*}
+    gogen test -t templates/api.ts.tmpl -update
+
+    # Check a template for typos in fields and functions
+    gogen lint-template templates/typescript.tmpl
+
+    # Show what gogen sees in an input file
+    gogen inspect -i models.go
+
+`, config.FileName)
 }
 
 func main() {
-	if err := run(); err != nil {
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if errors.Is(err, errReported) {
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
-	flag.Parse()
+// run dispatches to the command named by the first argument. Arguments
+// starting with a flag run the generate command, as gogen did before it had
+// subcommands.
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return flag.ErrHelp
+	}
+
+	name := args[0]
+	switch {
+	case name == "-h" || name == "-help" || name == "--help":
+		usage()
+		return flag.ErrHelp
+	case name == "help":
+		return runHelp(args[1:])
+	case strings.HasPrefix(name, "-"):
+		name = "generate"
+	default:
+		args = args[1:]
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		return fmt.Errorf("unknown command %q (run \"gogen help\" for usage)", name)
+	}
+	return cmd.run(newFlagSet(cmd), args)
+}
 
-	if showHelp {
-		flag.Usage()
+// runHelp prints the help of a command, or the general usage.
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		usage()
 		return nil
 	}
-
-	// Validate required flags
-	if inputFile == "" {
-		return fmt.Errorf("input file is required (-i or --input)")
-	}
-	if templateFile == "" {
-		return fmt.Errorf("template file is required (-t or --template)")
-	}
-
-	// Load configuration
-	cfg := config.New()
-	if configFile != "" {
-		if err := cfg.LoadFile(configFile); err != nil {
-			return fmt.Errorf("loading config: %w", err)
-		}
-	}
-
-	// Apply CLI overrides
-	if perType {
-		cfg.Options.PerType = true
-	}
-	cfg.Options.ExportedOnly = exportedOnly
-	if tagKey != "" {
-		cfg.Options.TagKey = tagKey
-	}
-	if types != "" {
-		cfg.Options.IncludeTypes = parseCommaSeparated(types)
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
 	}
-	if exclude != "" {
-		cfg.Options.ExcludeTypes = parseCommaSeparated(exclude)
-	}
-
-	// Parse input file
-	p := parser.New()
-	file, err := p.ParseFile(inputFile)
-	if err != nil {
-		return fmt.Errorf("parsing input: %w", err)
+	fs := newFlagSet(cmd)
+	err := cmd.run(fs, []string{"-h"})
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
 	}
+	return err
+}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Parsed %d types from %s\n", len(file.Types), inputFile)
-		for _, t := range file.Types {
-			fmt.Fprintf(os.Stderr, "  - %s (%s)\n", t.Name, t.Kind)
+// lookupCommand finds a command by name.
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
 	}
+	return command{}, false
+}
 
-	// Create generator and load template
-	gen := generator.New(cfg)
-	if err := gen.LoadTemplate(templateFile); err != nil {
-		return err
-	}
-
-	// Determine output destination
-	var output *os.File
-	if outputFile != "" {
-		output, err = os.Create(outputFile)
-		if err != nil {
-			return fmt.Errorf("creating output file: %w", err)
+// newFlagSet creates the flag set of a command, with per-command help.
+func newFlagSet(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gogen %s %s\n\n%s.\n", cmd.name, cmd.args, cmd.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(os.Stderr, "\nFlags:\n")
+			fs.PrintDefaults()
 		}
-		defer output.Close()
-	} else {
-		output = os.Stdout
 	}
-
-	// Generate output
-	if err := gen.Generate(file, output); err != nil {
-		return err
-	}
-
-	if verbose && outputFile != "" {
-		fmt.Fprintf(os.Stderr, "Generated output to %s\n", outputFile)
-	}
-
-	return nil
+	return fs
 }
 
 // parseCommaSeparated splits a comma-separated string into a slice of trimmed strings.
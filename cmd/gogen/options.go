@@ -0,0 +1,383 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gogen/internal/config"
+	"gogen/internal/diag"
+	"gogen/internal/generator"
+	"gogen/internal/model"
+	"gogen/internal/parser"
+)
+
+// options holds the flags shared by the commands that read Go input.
+type options struct {
+	input        string
+	template     string
+	configFile   string
+	output       string
+	perType      bool
+	exportedOnly bool
+	tagKey       string
+	types        string
+	exclude      string
+	localTypes   bool
+	pkg          bool
+	cacheDir     string
+	noCache      bool
+	verbose      bool
+	archive      string
+	jobs         int
+	format       string
+	strict       bool
+	flagArgs     []string // Flags that change the outputs, as given
+
+	diags   *diag.Collector       // Diagnostics reported by all targets
+	parses  *parser.Cache         // Inputs parsed once for all targets
+	locks   *generator.ProtoLocks // Proto lock files shared by all targets
+	stdinMu sync.Mutex
+	stdin   []byte // Input read from stdin, which can only be read once
+}
+
+// newOptions creates the options of a command.
+func newOptions() *options {
+	return &options{
+		diags:  &diag.Collector{},
+		parses: parser.NewCache(),
+		locks:  generator.NewProtoLocks(),
+	}
+}
+
+// stdinPath is the input path that reads Go source from stdin, and
+// stdinName the file path shown for it in positions and headers.
+const (
+	stdinPath = "-"
+	stdinName = "<stdin>"
+)
+
+// inputFlags registers the flags selecting and parsing the input.
+func (o *options) inputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.input, "input", "", "Input Go source file, package directory, or - for stdin (default under go generate: $GOFILE)")
+	fs.StringVar(&o.input, "i", "", "Input Go source file or package directory (shorthand)")
+	fs.BoolVar(&o.pkg, "package", false, "Under go generate, read the whole package ($GOPACKAGE) instead of $GOFILE")
+
+	fs.StringVar(&o.configFile, "config", "", "Config file (YAML/JSON, default: nearest "+config.FileName+")")
+	fs.StringVar(&o.configFile, "c", "", "Config file (shorthand)")
+
+	fs.BoolVar(&o.exportedOnly, "exported", true, "Only process exported types")
+	fs.StringVar(&o.tagKey, "tag", "json", "Tag key for field names")
+	fs.StringVar(&o.types, "types", "", "Only generate for these types (comma-separated)")
+	fs.StringVar(&o.types, "T", "", "Only generate for these types (shorthand)")
+	fs.StringVar(&o.exclude, "exclude", "", "Exclude these types (comma-separated)")
+	fs.StringVar(&o.exclude, "X", "", "Exclude these types (shorthand)")
+	fs.BoolVar(&o.localTypes, "local-types", false, "Also process types declared inside functions")
+	fs.BoolVar(&o.verbose, "v", false, "Verbose output")
+	o.diagFlags(fs)
+}
+
+// diagFlags registers the flags controlling how diagnostics are reported.
+func (o *options) diagFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "text", "Diagnostics format: text, or json for editors")
+	fs.BoolVar(&o.strict, "strict", false, "Fail on warnings")
+}
+
+// parseFlags parses the flags of a command and checks their values.
+func (o *options) parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if runOnlyFlags[f.Name] {
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			if f.Value.String() == "true" {
+				o.flagArgs = append(o.flagArgs, "-"+f.Name)
+			} else {
+				o.flagArgs = append(o.flagArgs, "-"+f.Name+"="+f.Value.String())
+			}
+			return
+		}
+		o.flagArgs = append(o.flagArgs, "-"+f.Name, f.Value.String())
+	})
+	if o.format != "text" && o.format != "json" {
+		return fmt.Errorf("unknown diagnostics format %q (want text or json)", o.format)
+	}
+	return nil
+}
+
+// errReported is returned by commands whose errors were already reported
+// as diagnostics.
+var errReported = errors.New("errors reported")
+
+// report prints the diagnostics collected while running a command, along
+// with the error the command failed with, to stderr. It returns
+// errReported if there are errors, or warnings with --strict.
+func (o *options) report(err error) error {
+	o.diags.Add(diag.FromError(err)...)
+	diags := o.diags.Diagnostics()
+
+	if o.format == "json" {
+		data, err := diag.JSON(diags)
+		if err != nil {
+			return fmt.Errorf("encoding diagnostics: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", data)
+	} else {
+		for _, d := range diags {
+			fmt.Fprintln(os.Stderr, d.String())
+		}
+	}
+
+	if o.diags.Count(diag.Error) > 0 {
+		return errReported
+	}
+	if n := o.diags.Count(diag.Warning); o.strict && n > 0 {
+		if o.format == "text" {
+			fmt.Fprintf(os.Stderr, "error: %d warning(s) with --strict\n", n)
+		}
+		return errReported
+	}
+	return nil
+}
+
+// generateFlags registers the flags of the commands that render templates.
+func (o *options) generateFlags(fs *flag.FlagSet) {
+	o.inputFlags(fs)
+
+	fs.StringVar(&o.template, "template", "", "Template file or built-in template name")
+	fs.StringVar(&o.template, "t", "", "Template file (shorthand)")
+
+	fs.StringVar(&o.output, "output", "", "Output file (default: stdout)")
+	fs.StringVar(&o.output, "o", "", "Output file (shorthand)")
+
+	fs.BoolVar(&o.perType, "per-type", false, "Execute template once per type")
+	fs.StringVar(&o.cacheDir, "cache-dir", "", "Directory of the generation cache (default: user cache directory)")
+	fs.BoolVar(&o.noCache, "no-cache", false, "Always regenerate, even if nothing changed")
+	fs.IntVar(&o.jobs, "j", runtime.GOMAXPROCS(0), "Number of targets generated in parallel")
+}
+
+// loadConfig loads the config file given with -c, or the nearest
+// .gogen.yaml, and applies the flag overrides.
+func (o *options) loadConfig() (*config.Config, error) {
+	cfg := config.New()
+
+	path := o.configFile
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("finding config file: %w", err)
+		}
+		if path, err = config.Find(wd); err != nil {
+			return nil, err
+		}
+		// Keep paths resolved from the config relative, so that they
+		// (and generated headers) do not depend on where the tree is
+		if rel, err := filepath.Rel(wd, path); err == nil && path != "" {
+			path = rel
+		}
+	}
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, fmt.Errorf("loading config: %w", err)
+		}
+		if o.verbose {
+			fmt.Fprintf(os.Stderr, "Using config %s\n", path)
+		}
+	}
+
+	// Apply CLI overrides
+	if o.perType {
+		cfg.Options.PerType = true
+	}
+	cfg.Options.ExportedOnly = o.exportedOnly
+	if o.tagKey != "" {
+		cfg.Options.TagKey = o.tagKey
+	}
+	if o.types != "" {
+		cfg.Options.IncludeTypes = parseCommaSeparated(o.types)
+	}
+	if o.exclude != "" {
+		cfg.Options.ExcludeTypes = parseCommaSeparated(o.exclude)
+	}
+	if o.localTypes {
+		cfg.Options.LocalTypes = true
+	}
+	return cfg, nil
+}
+
+// targets returns the targets to run: the one given by flags, or the ones
+// of the config file (only those named in names, if any).
+func (o *options) targets(cfg *config.Config, names []string) ([]config.Target, error) {
+	input := o.input
+	if input == "" && o.template != "" {
+		input = o.goGenerateInput()
+	}
+	if input != "" {
+		if o.template == "" {
+			return nil, fmt.Errorf("template file is required (-t or --template)")
+		}
+		return []config.Target{{Input: input, Template: o.template, Output: o.output}}, nil
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("input file is required (-i or --input), or configure targets in %s", config.FileName)
+	}
+	if len(names) == 0 {
+		return cfg.Targets, checkOutputs(cfg.Targets)
+	}
+
+	var targets []config.Target
+	for _, name := range names {
+		found := false
+		for _, t := range cfg.Targets {
+			if t.Name == name {
+				targets = append(targets, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown target %q", name)
+		}
+	}
+	return targets, checkOutputs(targets)
+}
+
+// checkOutputs reports targets writing the same output file, which would
+// overwrite each other in an order depending on scheduling.
+func checkOutputs(targets []config.Target) error {
+	seen := make(map[string]string)
+	for _, t := range targets {
+		if t.Output == "" {
+			continue
+		}
+		out := filepath.Clean(t.Output)
+		if name, ok := seen[out]; ok {
+			return fmt.Errorf("targets %q and %q both write %s", name, t.Name, t.Output)
+		}
+		seen[out] = t.Name
+	}
+	return nil
+}
+
+// targetConfig returns the config used to generate a target, with the
+// target's type filters applied.
+func targetConfig(cfg *config.Config, t config.Target) *config.Config {
+	c := *cfg
+	if len(t.Types) > 0 {
+		c.Options.IncludeTypes = t.Types
+	}
+	if len(t.Exclude) > 0 {
+		c.Options.ExcludeTypes = t.Exclude
+	}
+	if len(t.Variants) > 0 {
+		c.Options.Variants = t.Variants
+	}
+	return &c
+}
+
+// goGenerateInput returns the default input when gogen runs from a
+// //go:generate directive: the file holding the directive, or with
+// --package the package directory. It returns "" outside of go generate.
+func (o *options) goGenerateInput() string {
+	file := os.Getenv("GOFILE")
+	if file == "" {
+		return ""
+	}
+	if o.pkg {
+		return "."
+	}
+	return file
+}
+
+// parseInput parses an input file, the package in an input directory, or
+// the source read from stdin, as configured. Each input is parsed once,
+// however many targets use it.
+func (o *options) parseInput(cfg *config.Config, path string) (*model.File, error) {
+	key := fmt.Sprintf("%s\x00%t\x00%s", path, cfg.Options.LocalTypes, cfg.Options.TagKey)
+	return o.parses.Parse(key, func() (*model.File, error) {
+		parserOpts := []parser.Option{parser.WithDiagnostics(o.diags), parser.WithTagKey(cfg.Options.TagKey)}
+		if cfg.Options.LocalTypes {
+			parserOpts = append(parserOpts, parser.WithLocalTypes())
+		}
+		p := parser.New(parserOpts...)
+
+		var file *model.File
+		var err error
+		switch {
+		case path == stdinPath:
+			var src []byte
+			if src, err = o.readStdin(); err == nil {
+				file, err = p.ParseSource(stdinName, src)
+			}
+		case isDir(path):
+			// Under go generate, GOPACKAGE selects the package of the directive
+			file, err = p.ParseDir(path, os.Getenv("GOPACKAGE"))
+		default:
+			file, err = p.ParseFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing input: %w", err)
+		}
+		return file, nil
+	})
+}
+
+// readStdin reads the input from stdin, once for all targets.
+func (o *options) readStdin() ([]byte, error) {
+	o.stdinMu.Lock()
+	defer o.stdinMu.Unlock()
+	if o.stdin == nil {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+		o.stdin = data
+	}
+	return o.stdin, nil
+}
+
+// isDir reports whether path is a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// runOnlyFlags are the flags that change how gogen runs, but not what it
+// generates, and are left out of the command recorded in headers.
+var runOnlyFlags = map[string]bool{
+	"v":         true,
+	"j":         true,
+	"cache-dir": true,
+	"no-cache":  true,
+	"format":    true,
+	"strict":    true,
+	"archive":   true,
+}
+
+// commandLine returns the command that generates the output of a target, as
+// it would be typed in a shell: gogen generate with the flags that change
+// the outputs, and the target name if it has one. The command does not
+// depend on how gogen was run (e.g., gogen check, or with -v), so that
+// outputs compare equal whichever command renders them.
+func (o *options) commandLine(t config.Target) string {
+	args := []string{"gogen", "generate"}
+	args = append(args, o.flagArgs...)
+	if t.Name != "" {
+		args = append(args, t.Name)
+	}
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?;&|<>(){}[]#~") {
+			args[i] = strconv.Quote(arg)
+		}
+	}
+	return strings.Join(args, " ")
+}
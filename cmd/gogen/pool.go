@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+
+	"gogen/internal/config"
+)
+
+// targetResult collects what a target prints and produces, so that output
+// appears in target order whatever the scheduling.
+type targetResult struct {
+	log    bytes.Buffer // Messages for stderr
+	stdout bytes.Buffer // Output of targets without an output file
+	out    []byte       // Rendered output, for callers that use it
+	stale  bool         // Whether the output is out of date (check)
+	err    error
+}
+
+// runTargets runs a function for each target on a pool of o.jobs workers,
+// and returns the results in target order.
+func (o *options) runTargets(targets []config.Target, run func(config.Target, *targetResult) error) []*targetResult {
+	results := make([]*targetResult, len(targets))
+	for i := range results {
+		results[i] = &targetResult{}
+	}
+
+	jobs := o.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > len(targets) {
+		jobs = len(targets)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i].err = run(targets[i], results[i])
+			}
+		}()
+	}
+	for i := range targets {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// flushResults prints the messages and output of each target in target
+// order, and returns the errors of all failed targets, also in order.
+func flushResults(targets []config.Target, results []*targetResult) error {
+	var errs []error
+	for i, r := range results {
+		os.Stderr.Write(r.log.Bytes())
+		os.Stdout.Write(r.stdout.Bytes())
+		if r.err != nil {
+			errs = append(errs, targetError(targets[i], r.err))
+		}
+	}
+	return errors.Join(errs...)
+}
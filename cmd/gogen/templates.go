@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gogen/templates"
+)
+
+// runTemplates lists the built-in templates with their description, or
+// prints the source of the named one.
+func runTemplates(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		src, err := builtinTemplate(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, name := range templates.Names() {
+		src, err := builtinTemplate(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, templateDescription(src))
+	}
+	return w.Flush()
+}
+
+// builtinTemplate returns the source of a built-in template.
+func builtinTemplate(name string) ([]byte, error) {
+	file, ok := templates.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown built-in template %q (run \"gogen templates\" for the list)", name)
+	}
+	return fs.ReadFile(templates.FS, file)
+}
+
+// templateDescription returns the description of a template, given by a
+// {{- /* ... */ -}} comment on its first line.
+func templateDescription(src []byte) string {
+	line, _, _ := bytes.Cut(src, []byte("\n"))
+	desc, ok := strings.CutPrefix(strings.TrimSpace(string(line)), "{{- /*")
+	if !ok {
+		return ""
+	}
+	desc, ok = strings.CutSuffix(desc, "*/ -}}")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(desc)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"gogen/gogentest/golden"
+)
+
+// runTest runs the golden-file test cases of a directory against a
+// template.
+func runTest(fs *flag.FlagSet, args []string) error {
+	var tmpl string
+	var update, verbose bool
+	fs.StringVar(&tmpl, "template", "", "Template file or built-in template name")
+	fs.StringVar(&tmpl, "t", "", "Template file (shorthand)")
+	fs.BoolVar(&update, "update", false, "Rewrite expected files with the current output")
+	fs.BoolVar(&verbose, "v", false, "Verbose output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if tmpl == "" {
+		return fmt.Errorf("template file is required (-t or --template)")
+	}
+	dir := "testdata"
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	cases, err := golden.Discover(dir)
+	if err != nil {
+		return err
+	}
+	if len(cases) == 0 {
+		return fmt.Errorf("no test cases in %s (directories with %s)", dir, golden.InputFile)
+	}
+
+	failed := 0
+	for _, c := range cases {
+		err := c.Check(tmpl, update)
+		var mismatch *golden.Mismatch
+		switch {
+		case errors.As(err, &mismatch):
+			failed++
+			fmt.Fprintf(os.Stderr, "FAIL %s: output differs from %s\n%s", c.Name, mismatch.Expected, mismatch.Diff)
+		case err != nil:
+			failed++
+			fmt.Fprintf(os.Stderr, "FAIL %v\n", err)
+		case update:
+			fmt.Fprintf(os.Stderr, "updated %s\n", c.Name)
+		case verbose:
+			fmt.Fprintf(os.Stderr, "ok %s\n", c.Name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test case(s) failed (run with -update to accept the new output)", failed, len(cases))
+	}
+	if !update {
+		fmt.Fprintf(os.Stderr, "ok: %d test case(s) passed\n", len(cases))
+	}
+	return nil
+}
@@ -1,15 +1,32 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"gogen/gogentest"
+	"gogen/gogentest/golden"
+	"gogen/internal/cache"
 	"gogen/internal/config"
+	"gogen/internal/diag"
 	"gogen/internal/generator"
+	"gogen/internal/model"
 	"gogen/internal/parser"
+	"gogen/templates"
 )
 
 // TestE2E_TypeScriptGeneration tests the complete pipeline for TypeScript generation.
@@ -1297,3 +1314,2869 @@ func TestE2E_IntegrationWithExamples(t *testing.T) {
 		}
 	})
 }
+
+// TestE2E_ImportPathTypeMapping tests that aliased imports map by import path.
+func TestE2E_ImportPathTypeMapping(t *testing.T) {
+	inputContent := `package models
+
+import (
+	gouuid "github.com/gofrs/uuid"
+	dec "github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v4"
+)
+
+type Payment struct {
+	ID     gouuid.UUID   ` + "`json:\"id\"`" + `
+	Amount dec.Decimal   ` + "`json:\"amount\"`" + `
+	Note   null.String   ` + "`json:\"note\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+	templatePath := filepath.Join(tmpDir, "ts.tmpl")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	templateContent := `{{ range .Types -}}
+interface {{ .Name }} {
+{{- range .Fields }}
+  {{ tagOrName . }}: {{ mapType .Type }};
+{{- end }}
+}
+{{ end -}}
+`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.TypeMappings["gopkg.in/guregu/null.v4.String"] = "string | null"
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	fields := file.Types[0].Fields
+	if got := fields[0].Type.PkgPath; got != "github.com/gofrs/uuid" {
+		t.Errorf("expected PkgPath github.com/gofrs/uuid, got %q", got)
+	}
+	if got := fields[2].Type.PkgPath; got != "gopkg.in/guregu/null.v4" {
+		t.Errorf("expected PkgPath gopkg.in/guregu/null.v4, got %q", got)
+	}
+
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	output := buf.String()
+
+	tests := []struct {
+		name     string
+		contains string
+	}{
+		{"aliased uuid", "id: string;"},
+		{"aliased decimal", "amount: string;"},
+		{"versioned import path", "note: string | null;"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(output, tc.contains) {
+				t.Errorf("output does not contain %q\nGot:\n%s", tc.contains, output)
+			}
+		})
+	}
+
+	// Schemas recognize UUIDs by import path too
+	for template, want := range map[string]string{
+		"zod":     "id: z.string().uuid(),",
+		"valibot": "id: v.pipe(v.string(), v.uuid()),",
+	} {
+		gen := generator.New(cfg)
+		if err := gen.LoadTemplate(template); err != nil {
+			t.Fatalf("failed to load %s: %v", template, err)
+		}
+		buf.Reset()
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate %s: %v", template, err)
+		}
+		if !strings.Contains(buf.String(), want) || strings.Contains(buf.String(), "UUIDSchema") {
+			t.Errorf("%s output does not contain %q\nGot:\n%s", template, want, buf.String())
+		}
+	}
+}
+
+// TestE2E_TagOptionsAndTrailingComments tests full tag parsing and trailing comments.
+func TestE2E_TagOptionsAndTrailingComments(t *testing.T) {
+	inputContent := `package models
+
+type Person struct {
+	Name string ` + "`json:\"name\" example:\"Jane\" gorm:\"column:full_name;size:255\"`" + `
+	Age  int    ` + "`json:\"age,omitempty,string\" mapstructure:\"age\"`" + ` // years
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+	templatePath := filepath.Join(tmpDir, "ts.tmpl")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	templateContent := `{{ range .Types -}}
+interface {{ .Name }} {
+{{- range .Fields }}
+  {{ tagName . "json" }}{{ if hasTagOption . "json" "omitempty" }}?{{ end }}: {{ mapType .Type }};{{ if .Comment }} // {{ .Comment }}{{ end }}
+  // example={{ tag . "example" }} options={{ join (tagOptions . "json") "|" }}
+{{- end }}
+}
+{{ end -}}
+`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+
+	cfg := config.New()
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	tag := file.Types[0].Fields[0].Tag
+	if got := strings.Join(tag.Keys, ","); got != "json,example,gorm" {
+		t.Errorf("expected tag keys json,example,gorm, got %q", got)
+	}
+	if got := tag.Values["gorm"]; got != "column:full_name;size:255" {
+		t.Errorf("expected gorm tag preserved, got %q", got)
+	}
+
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	output := buf.String()
+
+	tests := []struct {
+		name     string
+		contains string
+	}{
+		{"arbitrary tag key", "example=Jane"},
+		{"trailing comment", "age?: number; // years"},
+		{"tag options", "options=omitempty|string"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(output, tc.contains) {
+				t.Errorf("output does not contain %q\nGot:\n%s", tc.contains, output)
+			}
+		})
+	}
+}
+
+// TestE2E_InlineCompositeTypes tests anonymous structs, fixed arrays, channels and funcs.
+func TestE2E_InlineCompositeTypes(t *testing.T) {
+	inputContent := `package models
+
+type Shape struct {
+	Meta struct {
+		A int    ` + "`json:\"a\"`" + `
+		B string ` + "`json:\"b,omitempty\"`" + `
+	} ` + "`json:\"meta\"`" + `
+	Point   [3]float64  ` + "`json:\"point\"`" + `
+	Events  chan int    ` + "`json:\"-\"`" + `
+	OnClick func() error ` + "`json:\"-\"`" + `
+	Digest  [32]uint8   ` + "`json:\"digest\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	cfg := config.New()
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	fields := file.Types[0].Fields
+	if fields[0].Type.Kind != model.KindStruct || len(fields[0].Type.Fields) != 2 {
+		t.Errorf("expected anonymous struct with 2 fields, got %+v", fields[0].Type)
+	}
+	if fields[1].Type.Kind != model.KindArray || fields[1].Type.Len != 3 || fields[1].Type.Raw != "[3]float64" {
+		t.Errorf("expected [3]float64 array, got %+v", fields[1].Type)
+	}
+	if fields[2].Type.Kind != model.KindChan {
+		t.Errorf("expected chan kind, got %s", fields[2].Type.Kind)
+	}
+	if fields[3].Type.Kind != model.KindFunc {
+		t.Errorf("expected func kind, got %s", fields[3].Type.Kind)
+	}
+
+	templates := map[string][]string{
+		"templates/typescript.tmpl": {
+			"meta: { a: number; b?: string };",
+			"point: [number, number, number];",
+			"digest: number[];", // Long arrays are not tuples
+		},
+		"templates/zod.tmpl": {
+			"meta: z.object({ a: z.number(), b: z.string().optional(), })",
+			"point: z.tuple([z.number(), z.number(), z.number()])",
+			"digest: z.array(z.number())",
+		},
+		"templates/valibot.tmpl": {
+			"meta: v.object({ a: v.number(), b: v.optional(v.string()), })",
+			"point: v.tuple([v.number(), v.number(), v.number()])",
+			"digest: v.array(v.number())",
+		},
+	}
+
+	for templatePath, expected := range templates {
+		t.Run(filepath.Base(templatePath), func(t *testing.T) {
+			gen := generator.New(cfg)
+			if err := gen.LoadTemplate(templatePath); err != nil {
+				t.Fatalf("failed to load template: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := gen.Generate(file, &buf); err != nil {
+				t.Fatalf("failed to generate: %v", err)
+			}
+
+			output := buf.String()
+			for _, want := range expected {
+				if !strings.Contains(output, want) {
+					t.Errorf("output does not contain %q\nGot:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+// TestE2E_SourcePositions tests source positions on types, fields and template errors.
+func TestE2E_SourcePositions(t *testing.T) {
+	inputContent := `package models
+
+// User is a user.
+type User struct {
+	ID   string ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type Broken struct {
+	Data map[string]int ` + "`json:\"data\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "models.go")
+	templatePath := filepath.Join(tmpDir, "ts.tmpl")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	templateContent := `{{ range .Types -}}
+{{ sourceComment .Pos }}
+interface {{ .Name }} {
+{{- range .Fields }}
+  {{ tagOrName . }}: {{ mapType .Type }}; // {{ source .Pos }}
+{{- end }}
+}
+{{ end -}}
+`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+
+	cfg := config.New()
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	user := file.Types[0]
+	if user.Pos.Line != 4 || user.Pos.Column != 6 {
+		t.Errorf("expected User at 4:6, got %s", user.Pos)
+	}
+	if user.Fields[1].Pos.Line != 6 {
+		t.Errorf("expected Name field on line 6, got %s", user.Fields[1].Pos)
+	}
+
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"// source: models.go:4", "name: string; // models.go:6"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q\nGot:\n%s", want, output)
+		}
+	}
+
+	// A template that fails only for map fields should report Broken's declaration
+	failingContent := `{{ range .Types }}{{ range .Fields }}{{ if isMap .Type }}{{ .Type.Key.Missing }}{{ end }}{{ end }}{{ end }}`
+	if err := os.WriteFile(templatePath, []byte(failingContent), 0644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+
+	err = gen.Generate(file, io.Discard)
+	if err == nil {
+		t.Fatal("expected template execution error")
+	}
+	if !strings.Contains(err.Error(), "Broken (models.go:9)") {
+		t.Errorf("expected error to point at Broken (models.go:9), got: %v", err)
+	}
+
+	// A template failing whatever the types is not blamed on a type
+	if err := os.WriteFile(templatePath, []byte(`{{ .File.Missing }}{{ range .Types }}{{ .Name }}{{ end }}`), 0644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	err = gen.Generate(file, io.Discard)
+	if err == nil || strings.Contains(err.Error(), "executing template for") {
+		t.Errorf("expected a template error without a type, got: %v", err)
+	}
+}
+
+// TestE2E_LocalTypes tests that types declared inside functions are ignored by default.
+func TestE2E_LocalTypes(t *testing.T) {
+	inputContent := `package models
+
+type User struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+
+func handleUser() {
+	type Payload struct {
+		User  User   ` + "`json:\"user\"`" + `
+		Extra *Extra ` + "`json:\"extra\"`" + `
+	}
+	type Extra struct {
+		Note string ` + "`json:\"note\"`" + `
+	}
+}
+
+type Server struct{}
+
+func (s *Server) Handle() {
+	type User struct {
+		Name string ` + "`json:\"name\"`" + `
+	}
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	typeNames := func(file *model.File) []string {
+		var names []string
+		for _, typ := range file.Types {
+			names = append(names, typ.Name)
+		}
+		return names
+	}
+
+	t.Run("package scope only (default)", func(t *testing.T) {
+		file, err := parser.New().ParseFile(inputPath)
+		if err != nil {
+			t.Fatalf("failed to parse file: %v", err)
+		}
+		if got := strings.Join(typeNames(file), ","); got != "User,Server" {
+			t.Errorf("expected User,Server, got %s", got)
+		}
+	})
+
+	t.Run("local types namespaced", func(t *testing.T) {
+		file, err := parser.New(parser.WithLocalTypes()).ParseFile(inputPath)
+		if err != nil {
+			t.Fatalf("failed to parse file: %v", err)
+		}
+		got := strings.Join(typeNames(file), ",")
+		if got != "User,handleUser_Payload,handleUser_Extra,Server,Server_Handle_User" {
+			t.Fatalf("unexpected types: %s", got)
+		}
+
+		payload := file.Types[1]
+		if name := payload.Fields[0].Type.Name; name != "User" {
+			t.Errorf("expected package-scope User reference, got %s", name)
+		}
+		if raw := payload.Fields[1].Type.Raw; raw != "*handleUser_Extra" {
+			t.Errorf("expected reference to renamed local type, got %s", raw)
+		}
+	})
+}
+
+// TestE2E_PydanticGeneration tests the built-in Pydantic v2 target.
+func TestE2E_PydanticGeneration(t *testing.T) {
+	inputContent := `package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Role is a user role.
+type Role string
+
+const (
+	RoleAdmin Role = "admin"
+	RoleGuest Role = "guest"
+)
+
+type Status int
+
+const (
+	StatusPending Status = iota + 1
+	StatusActive
+)
+
+// Account is a user account.
+type Account struct {
+	ID        uuid.UUID       ` + "`json:\"id\"`" + `
+	Email     string          ` + "`json:\"email\" validate:\"required,email\"`" + `
+	Name      string          ` + "`json:\"name\" validate:\"min=2,max=40\"`" + `
+	Role      Role            ` + "`json:\"role\"`" + `
+	Status    Status          ` + "`json:\"status\"`" + `
+	Balance   decimal.Decimal ` + "`json:\"balance\"`" + `
+	CreatedAt time.Time       ` + "`json:\"createdAt\"`" + `
+	Parent    *Account        ` + "`json:\"parent,omitempty\"`" + `
+	Secret    string          ` + "`json:\"-\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	cfg := config.New()
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	status := file.Types[1]
+	if len(status.Constants) != 2 || status.Constants[1].Value != "2" {
+		t.Errorf("expected Status constants evaluated from iota, got %+v", status.Constants)
+	}
+
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate("pydantic"); err != nil {
+		t.Fatalf("failed to load built-in template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	output := buf.String()
+
+	tests := []struct {
+		name     string
+		contains string
+	}{
+		{"future annotations", "from __future__ import annotations"},
+		{"datetime import", "from datetime import datetime"},
+		{"decimal import", "from decimal import Decimal"},
+		{"uuid import", "from uuid import UUID"},
+		{"pydantic import", "from pydantic import BaseModel, ConfigDict, EmailStr, Field"},
+		{"string enum", "class Role(str, Enum):\n    \"\"\"Role is a user role.\"\"\"\n\n    ADMIN = \"admin\""},
+		{"int enum", "class Status(IntEnum):\n    PENDING = 1\n    ACTIVE = 2"},
+		{"model class", "class Account(BaseModel):"},
+		{"email validator", "email: EmailStr"},
+		{"length constraints", "name: str = Field(min_length=2, max_length=40)"},
+		{"alias", "created_at: datetime = Field(alias=\"createdAt\")"},
+		{"recursive optional", "parent: Optional[Account] = None"},
+		{"forward references", "Account.model_rebuild()"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(output, tc.contains) {
+				t.Errorf("output does not contain %q\nGot:\n%s", tc.contains, output)
+			}
+		})
+	}
+
+	if strings.Contains(output, "secret") {
+		t.Errorf("fields tagged json:\"-\" should be skipped\nGot:\n%s", output)
+	}
+}
+
+// TestE2E_RustGeneration tests the built-in Rust serde target.
+func TestE2E_RustGeneration(t *testing.T) {
+	inputContent := `package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Timestamps struct {
+	CreatedAt time.Time ` + "`json:\"createdAt\"`" + `
+}
+
+type Node struct {
+	ID       uuid.UUID         ` + "`json:\"id\"`" + `
+	Type     string            ` + "`json:\"type\"`" + `
+	Children []Node            ` + "`json:\"children\"`" + `
+	Labels   map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	Parent   *Node             ` + "`json:\"parent\"`" + `
+	Timestamps
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	cfg := config.New()
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate("rust"); err != nil {
+		t.Fatalf("failed to load built-in template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	output := buf.String()
+
+	tests := []struct {
+		name     string
+		contains string
+	}{
+		{"serde import", "use serde::{Deserialize, Serialize};"},
+		{"chrono import", "use chrono::{DateTime, Utc};"},
+		{"hashmap import", "use std::collections::HashMap;"},
+		{"derive", "#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]\npub struct Node {"},
+		{"rename", "    #[serde(rename = \"createdAt\")]\n    pub created_at: DateTime<Utc>,"},
+		{"uuid", "pub id: Uuid,"},
+		{"keyword field", "pub r#type: String,"},
+		{"vec", "pub children: Vec<Node>,"},
+		{"omitempty option", "    #[serde(default, skip_serializing_if = \"Option::is_none\")]\n    pub labels: Option<HashMap<String, String>>,"},
+		{"boxed recursion", "pub parent: Option<Box<Node>>,"},
+		{"flatten embedded", "    #[serde(flatten)]\n    pub timestamps: Timestamps,"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(output, tc.contains) {
+				t.Errorf("output does not contain %q\nGot:\n%s", tc.contains, output)
+			}
+		})
+	}
+}
+
+// TestE2E_MobileTargets tests the built-in Kotlin and Swift targets.
+func TestE2E_MobileTargets(t *testing.T) {
+	inputContent := `package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleAdmin Role = "admin"
+	RoleGuest Role = "guest"
+)
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota + 1
+	PriorityHigh
+)
+
+type Profile struct {
+	ID        uuid.UUID  ` + "`json:\"id\"`" + `
+	Role      Role       ` + "`json:\"role\"`" + `
+	CreatedAt time.Time  ` + "`json:\"created_at\"`" + `
+	Nickname  *string    ` + "`json:\"nickname\"`" + `
+	Bio       string     ` + "`json:\"bio,omitempty\"`" + `
+	Scores    []int      ` + "`json:\"scores\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	cfg := config.New()
+	p := parser.New()
+
+	file, err := p.ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	targets := map[string][]string{
+		"kotlin": {
+			"import kotlinx.datetime.Instant",
+			"import kotlinx.serialization.Serializable",
+			"@Serializable\nenum class Role {\n    @SerialName(\"admin\")\n    ADMIN,",
+			// Int enums are encoded as numbers, like encoding/json does
+			"@Serializable(with = PrioritySerializer::class)\nenum class Priority(val value: Long) {\n    LOW(1),\n    HIGH(2),\n}",
+			"object PrioritySerializer : KSerializer<Priority> {",
+			"PrimitiveSerialDescriptor(\"Priority\", PrimitiveKind.LONG)",
+			"encoder.encodeLong(value.value)",
+			"val value = decoder.decodeLong()\n        return Priority.entries.firstOrNull { it.value == value }",
+			"import kotlinx.serialization.KSerializer",
+			"import kotlinx.serialization.encoding.Decoder",
+			"@Serializable\ndata class Profile(",
+			"    @SerialName(\"created_at\")\n    val createdAt: Instant,",
+			"val id: String,",
+			"val nickname: String? = null,",
+			"val bio: String? = null,",
+			"val scores: List<Long>,",
+		},
+		"swift": {
+			"import Foundation",
+			"public enum Role: String, Codable {\n    case admin = \"admin\"",
+			"public struct Profile: Codable {",
+			"public let id: UUID",
+			"public let createdAt: Date",
+			"public let nickname: String?",
+			"public let bio: String?",
+			"public let scores: [Int]",
+			"enum CodingKeys: String, CodingKey {",
+			"case createdAt = \"created_at\"",
+		},
+	}
+
+	for target, expected := range targets {
+		t.Run(target, func(t *testing.T) {
+			gen := generator.New(cfg)
+			if err := gen.LoadTemplate(target); err != nil {
+				t.Fatalf("failed to load built-in template: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := gen.Generate(file, &buf); err != nil {
+				t.Fatalf("failed to generate: %v", err)
+			}
+
+			output := buf.String()
+			for _, want := range expected {
+				if !strings.Contains(output, want) {
+					t.Errorf("output does not contain %q\nGot:\n%s", want, output)
+				}
+			}
+		})
+	}
+
+	t.Run("per-target mapping override", func(t *testing.T) {
+		cfg := config.New()
+		cfg.TargetMappings["swift"]["github.com/google/uuid.UUID"] = "String"
+
+		gen := generator.New(cfg)
+		if err := gen.LoadTemplate("swift"); err != nil {
+			t.Fatalf("failed to load built-in template: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+
+		if !strings.Contains(buf.String(), "public let id: String") {
+			t.Errorf("expected mapping override to apply\nGot:\n%s", buf.String())
+		}
+	})
+}
+
+// TestE2E_ProtoGeneration tests the built-in proto3 target and its lock file.
+func TestE2E_ProtoGeneration(t *testing.T) {
+	inputV1 := `package models
+
+import "time"
+
+type Status int
+
+const (
+	StatusActive Status = iota
+	StatusBanned
+)
+
+type User struct {
+	ID        string            ` + "`json:\"id\"`" + `
+	Email     string            ` + "`json:\"email\"`" + `
+	Status    Status            ` + "`json:\"status\"`" + `
+	Tags      []string          ` + "`json:\"tags\"`" + `
+	Labels    map[string]string ` + "`json:\"labels\"`" + `
+	CreatedAt time.Time         ` + "`json:\"createdAt\"`" + `
+	Manager   *User             ` + "`json:\"manager\"`" + `
+	Avatar    []byte            ` + "`json:\"avatar_data\"`" + `
+}
+`
+	// Email is removed and Phone is added in the second version
+	inputV2 := strings.Replace(inputV1, "Email     string            `json:\"email\"`",
+		"Phone     string            `json:\"phone\"`", 1)
+
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+	lockPath := filepath.Join(tmpDir, "gogen.proto.lock")
+
+	cfg := config.New()
+	cfg.Options.ProtoPackage = "api.v1"
+	cfg.Options.ProtoLock = lockPath
+
+	generate := func(input string) string {
+		if err := os.WriteFile(inputPath, []byte(input), 0644); err != nil {
+			t.Fatalf("failed to write input file: %v", err)
+		}
+		file, err := parser.New().ParseFile(inputPath)
+		if err != nil {
+			t.Fatalf("failed to parse file: %v", err)
+		}
+		gen := generator.New(cfg)
+		if err := gen.LoadTemplate("proto"); err != nil {
+			t.Fatalf("failed to load built-in template: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		// Generating alone leaves the lock file alone, e.g., for gogen check
+		if _, err := os.Stat(lockPath); err == nil && input == inputV1 {
+			t.Fatalf("lock file written before SaveProtoLock")
+		}
+		if err := gen.SaveProtoLock(); err != nil {
+			t.Fatalf("failed to save proto lock: %v", err)
+		}
+		return buf.String()
+	}
+
+	output := generate(inputV1)
+
+	for _, want := range []string{
+		"syntax = \"proto3\";",
+		"package api.v1;",
+		"import \"google/protobuf/timestamp.proto\";",
+		"enum Status {\n  STATUS_UNSPECIFIED = 0;\n  STATUS_ACTIVE = 1;\n  STATUS_BANNED = 2;\n}",
+		"  string id = 1;",
+		"  string email = 2;",
+		"  Status status = 3;",
+		"  repeated string tags = 4;",
+		"  map<string, string> labels = 5;",
+		"  google.protobuf.Timestamp created_at = 6;",
+		"  optional User manager = 7;",
+		"  bytes avatar = 8 [json_name = \"avatar_data\"];",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q\nGot:\n%s", want, output)
+		}
+	}
+
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("expected lock file to be written: %v", err)
+	}
+
+	output = generate(inputV2)
+
+	for _, want := range []string{
+		"  reserved 2;\n  reserved \"email\";",
+		"  string phone = 9;",
+		"  Status status = 3;",
+		"  bytes avatar = 8",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("second run output does not contain %q\nGot:\n%s", want, output)
+		}
+	}
+
+	// Repeated maps and maps of maps cannot be expressed, and are reported
+	nested := `package models
+
+type Report struct {
+	Rows   []map[string]int          ` + "`json:\"rows\"`" + `
+	Groups map[string]map[string]int ` + "`json:\"groups\"`" + `
+	Matrix [][]int                   ` + "`json:\"matrix\"`" + `
+	Tasks  []*Task                   ` + "`json:\"tasks\"`" + `
+	ByName map[string]*Task          ` + "`json:\"byName\"`" + `
+}
+
+type Task struct {
+	Subs []*Task ` + "`json:\"subs\"`" + `
+}
+`
+	if err := os.WriteFile(inputPath, []byte(nested), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	diags := &diag.Collector{}
+	gen := generator.New(config.New())
+	gen.SetDiagnostics(diags)
+	if err := gen.LoadTemplate("proto"); err != nil {
+		t.Fatalf("failed to load built-in template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	for _, want := range []string{
+		"import \"google/protobuf/struct.proto\";",
+		"  repeated google.protobuf.Struct rows = 1;",
+		"  map<string, google.protobuf.Struct> groups = 2;",
+		"  repeated google.protobuf.Value matrix = 3;",
+		"  repeated Task tasks = 4;",
+		"  map<string, Task> by_name = 5;",
+		"  repeated Task subs = 1;",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q\nGot:\n%s", want, buf.String())
+		}
+	}
+	var lines []string
+	for _, d := range diags.Diagnostics() {
+		lines = append(lines, fmt.Sprintf("%d %s", d.Pos.Line, d.Code))
+	}
+	if got := strings.Join(lines, ", "); got != "4 unsupported-type, 5 unsupported-type, 6 unsupported-type" {
+		t.Errorf("unexpected diagnostics: %s", got)
+	}
+
+	// Targets of a run share the lock file, which is saved once with the
+	// numbers of all of them
+	sharedPath := filepath.Join(tmpDir, "shared.proto.lock")
+	sharedCfg := config.New()
+	sharedCfg.Options.ProtoLock = sharedPath
+	locks := generator.NewProtoLocks()
+	var wg sync.WaitGroup
+	for _, name := range []string{"Alpha", "Beta", "Gamma", "Delta"} {
+		src := "package models\n\ntype " + name + " struct {\n\tID string `json:\"id\"`\n}\n"
+		file, err := parser.New().ParseSource(name+".go", []byte(src))
+		if err != nil {
+			t.Fatalf("failed to parse source: %v", err)
+		}
+		gen := generator.New(sharedCfg)
+		gen.SetProtoLocks(locks)
+		if err := gen.LoadTemplate("proto"); err != nil {
+			t.Fatalf("failed to load built-in template: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := gen.Generate(file, io.Discard); err != nil {
+				t.Errorf("failed to generate: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := os.Stat(sharedPath); err == nil {
+		t.Fatal("shared lock file written before Save")
+	}
+	if err := locks.Save(); err != nil {
+		t.Fatalf("failed to save proto locks: %v", err)
+	}
+	data, err := os.ReadFile(sharedPath)
+	if err != nil {
+		t.Fatalf("failed to read shared lock file: %v", err)
+	}
+	for _, name := range []string{"Alpha", "Beta", "Gamma", "Delta"} {
+		if !strings.Contains(string(data), "\""+name+"\"") {
+			t.Errorf("shared lock file does not contain %s:\n%s", name, data)
+		}
+	}
+}
+
+// TestE2E_GraphQLGeneration tests the built-in GraphQL SDL target.
+func TestE2E_GraphQLGeneration(t *testing.T) {
+	inputContent := `package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Visibility controls who can see a post.
+type Visibility string
+
+const (
+	// VisibilityPublic is visible to everyone.
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+type Slug string
+
+// Post is a blog post.
+type Post struct {
+	// ID identifies the post.
+	ID         uuid.UUID  ` + "`json:\"id\"`" + `
+	Slug       Slug       ` + "`json:\"slug\"`" + `
+	Title      string     ` + "`json:\"title\"`" + `
+	Tags       []string   ` + "`json:\"tags\"`" + `
+	Related    []*Post    ` + "`json:\"related\"`" + `
+	Visibility Visibility ` + "`json:\"visibility\"`" + `
+	Author     Author     ` + "`json:\"author\"`" + `
+	EditedAt   *time.Time ` + "`json:\"editedAt\"`" + `
+	Views      int        ` + "`json:\"views,omitempty\"`" + `
+	Bytes      int64      ` + "`json:\"bytes\"`" + `
+	Hits       uint       ` + "`json:\"hits\"`" + `
+	Price      decimal.Decimal ` + "`json:\"price\"`" + `
+	Rating     float64    ` + "`json:\"rating\"`" + `
+}
+
+type Author struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.TargetMappings["graphql"]["time.Time"] = "Time"
+	cfg.TypeMappings["decimal.Decimal"] = "Decimal"
+	cfg.TypeMappings["float64"] = "number" // A TypeScript type, not used
+	cfg.Options.GraphQLInput = true
+
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate("graphql"); err != nil {
+		t.Fatalf("failed to load built-in template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	output := buf.String()
+
+	tests := []struct {
+		name     string
+		contains string
+	}{
+		{"custom scalars", "scalar Decimal\nscalar Int64\nscalar Time\nscalar UUID\nscalar Uint64"},
+		{"64-bit integer", "bytes: Int64!"},
+		{"64-bit unsigned integer", "hits: Uint64!"},
+		{"typeMappings scalar", "price: Decimal!"},
+		{"typeMappings TypeScript type", "rating: Float!"},
+		{"enum", "\"\"\"Visibility controls who can see a post.\"\"\"\nenum Visibility {\n  \"\"\"VisibilityPublic is visible to everyone.\"\"\"\n  PUBLIC\n  PRIVATE\n}"},
+		{"type description", "\"\"\"Post is a blog post.\"\"\"\ntype Post {"},
+		{"field description", "  \"\"\"ID identifies the post.\"\"\"\n  id: UUID!"},
+		{"named scalar resolved", "slug: String!"},
+		{"non-null list", "tags: [String!]!"},
+		{"nullable elements", "related: [Post]!"},
+		{"object reference", "author: Author!"},
+		{"nullable pointer", "editedAt: Time\n"},
+		{"omitempty nullable", "views: Int\n"},
+		{"input type", "input PostInput {"},
+		{"input reference", "author: AuthorInput!"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(output, tc.contains) {
+				t.Errorf("output does not contain %q\nGot:\n%s", tc.contains, output)
+			}
+		})
+	}
+}
+
+// TestE2E_SQLGeneration tests the built-in Postgres and SQLite DDL targets.
+func TestE2E_SQLGeneration(t *testing.T) {
+	inputContent := `package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusDisabled Status = "disabled"
+)
+
+// Post is written by a user.
+type Post struct {
+	ID       int64     ` + "`db:\"id\"`" + `
+	AuthorID uuid.UUID ` + "`db:\"author_id\" sql:\"fk=User\"`" + `
+	Title    string    ` + "`db:\"title\" validate:\"required,max=200\"`" + `
+	Body     *string   ` + "`db:\"body\"`" + `
+	Tags     []string  ` + "`db:\"tags\"`" + `
+	Draft    bool      ` + "`db:\"-\"`" + `
+}
+
+// User is an account.
+//
+//gogen:table accounts
+type User struct {
+	//gogen:pk
+	UUID      uuid.UUID  ` + "`db:\"uuid\"`" + `
+	Email     string     ` + "`db:\"email\" sql:\"unique\"`" + `
+	Status    Status     ` + "`db:\"status\"`" + `
+	Nickname  *string    ` + "`db:\"nickname\" validate:\"required\"`" + `
+	DeletedAt *time.Time ` + "`db:\"deleted_at\"`" + `
+	Score     float64    ` + "`db:\"score\" sql:\"index\"`" + `
+}
+
+// Payload has no db tags and is not a table.
+type Payload struct {
+	Data string ` + "`json:\"data\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	generate := func(template string) string {
+		gen := generator.New(config.New())
+		if err := gen.LoadTemplate(template); err != nil {
+			t.Fatalf("failed to load built-in template %s: %v", template, err)
+		}
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate %s: %v", template, err)
+		}
+		return buf.String()
+	}
+
+	postgres := generate("sql-postgres")
+	tests := []struct {
+		name     string
+		contains string
+	}{
+		{"table from directive", "-- User is an account.\nCREATE TABLE IF NOT EXISTS accounts (\n  uuid UUID NOT NULL,"},
+		{"enum resolved", "  status TEXT NOT NULL,"},
+		{"required pointer", "  nickname TEXT NOT NULL,"},
+		{"nullable pointer", "  deleted_at TIMESTAMPTZ,"},
+		{"primary key directive", "  PRIMARY KEY (uuid)\n);"},
+		{"unique index", "CREATE UNIQUE INDEX IF NOT EXISTS accounts_email_key ON accounts (email);"},
+		{"index", "CREATE INDEX IF NOT EXISTS accounts_score_idx ON accounts (score);"},
+		{"pluralized table", "CREATE TABLE IF NOT EXISTS posts ("},
+		{"varchar", "  title VARCHAR(200) NOT NULL,"},
+		{"nullable column", "  body TEXT,"},
+		{"json column", "  tags JSONB NOT NULL,"},
+		{"default primary key", "  PRIMARY KEY (id),"},
+		{"foreign key", "  FOREIGN KEY (author_id) REFERENCES accounts (uuid)\n);"},
+	}
+	for _, tt := range tests {
+		t.Run("postgres/"+tt.name, func(t *testing.T) {
+			if !strings.Contains(postgres, tt.contains) {
+				t.Errorf("expected output to contain %q\n\nGot:\n%s", tt.contains, postgres)
+			}
+		})
+	}
+
+	if strings.Index(postgres, "CREATE TABLE IF NOT EXISTS accounts") > strings.Index(postgres, "CREATE TABLE IF NOT EXISTS posts") {
+		t.Errorf("expected referenced table to be created first\n\nGot:\n%s", postgres)
+	}
+	if strings.Contains(postgres, "draft") || strings.Contains(postgres, "payload") {
+		t.Errorf("expected skipped columns and tables to be absent\n\nGot:\n%s", postgres)
+	}
+
+	sqlite := generate("sql-sqlite")
+	for _, want := range []string{
+		"  uuid TEXT NOT NULL,",
+		"  deleted_at TIMESTAMP,",
+		"  score REAL NOT NULL,",
+		"  tags TEXT NOT NULL,",
+		"  title VARCHAR(200) NOT NULL,",
+	} {
+		if !strings.Contains(sqlite, want) {
+			t.Errorf("expected sqlite output to contain %q\n\nGot:\n%s", want, sqlite)
+		}
+	}
+
+	// References to types that are not tables are reported
+	bad := strings.Replace(inputContent, `fk=User`, `fk=Payload`, 1)
+	if err := os.WriteFile(inputPath, []byte(bad), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+	file, err = parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	gen := generator.New(config.New())
+	if err := gen.LoadTemplate("sql-postgres"); err != nil {
+		t.Fatalf("failed to load built-in template: %v", err)
+	}
+	if err := gen.Generate(file, io.Discard); err == nil || !strings.Contains(err.Error(), "references Payload") {
+		t.Errorf("expected invalid foreign key error, got %v", err)
+	}
+}
+
+// TestE2E_GoGeneration tests the built-in Go templates and the formatting
+// and import fixing applied to Go output.
+func TestE2E_GoGeneration(t *testing.T) {
+	inputContent := `package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Role string
+
+type Address struct {
+	Street string ` + "`json:\"street\" validate:\"required\"`" + `
+}
+
+type User struct {
+	ID        uuid.UUID         ` + "`json:\"id\"`" + `
+	Email     string            ` + "`json:\"email\" validate:\"required,email,max=100\"`" + `
+	Role      Role              ` + "`json:\"role\" validate:\"oneof=admin user\"`" + `
+	Nickname  *string           ` + "`json:\"nickname\" validate:\"omitempty,min=3\"`" + `
+	Website   string            ` + "`json:\"website\" validate:\"omitempty,url\"`" + `
+	Tags      []string          ` + "`json:\"tags\" validate:\"max=5\"`" + `
+	Address   Address           ` + "`json:\"address\"`" + `
+	History   []*Address        ` + "`json:\"history\"`" + `
+	Scores    map[string][]int  ` + "`json:\"scores\"`" + `
+	CreatedAt time.Time         ` + "`json:\"createdAt\" validate:\"required\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	generate := func(template string) string {
+		gen := generator.New(config.New())
+		if err := gen.LoadTemplate(template); err != nil {
+			t.Fatalf("failed to load template %s: %v", template, err)
+		}
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate %s: %v", template, err)
+		}
+		formatted, err := format.Source(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s output is not valid Go: %v\n\n%s", template, err, buf.String())
+		}
+		if !bytes.Equal(formatted, buf.Bytes()) {
+			t.Errorf("%s output is not gofmt-formatted:\n%s", template, buf.String())
+		}
+		return buf.String()
+	}
+
+	tests := []struct {
+		template string
+		contains string
+	}{
+		{"go-validate", "import (\n\t\"errors\"\n\t\"fmt\"\n\t\"net/mail\"\n\t\"net/url\"\n\t\"unicode/utf8\"\n)"},
+		{"go-validate", "func (u *User) Validate() error {\n\tvar errs []error\n"},
+		{"go-validate", "\tif u.Email == \"\" {\n\t\terrs = append(errs, errors.New(\"email: is required\"))\n\t}"},
+		{"go-validate", "if _, err := mail.ParseAddress(string(u.Email)); err != nil {"},
+		{"go-validate", "if utf8.RuneCountInString(string(u.Email)) > 100 {"},
+		{"go-validate", "\tswitch u.Role {\n\tcase \"admin\", \"user\":\n\tdefault:"},
+		{"go-validate", "\tif u.Nickname != nil {\n\t\tif utf8.RuneCountInString(string(*u.Nickname)) < 3 {"},
+		{"go-validate", "\tif u.Website != \"\" {\n\t\tif _, err := url.ParseRequestURI(string(u.Website)); err != nil {"},
+		{"go-validate", "if len(u.Tags) > 5 {"},
+		{"go-validate", "if err := u.Address.Validate(); err != nil {\n\t\terrs = append(errs, fmt.Errorf(\"address: %w\", err))"},
+		{"go-validate", "if u.CreatedAt.IsZero() {"},
+		{"go-validate", "\treturn errors.Join(errs...)\n}"},
+		{"go-options", "import (\n\t\"time\"\n\n\t\"github.com/google/uuid\"\n)"},
+		{"go-options", "type UserOption func(*User)"},
+		{"go-options", "func NewUser(opts ...UserOption) *User {"},
+		{"go-options", "func WithUserID(v uuid.UUID) UserOption {\n\treturn func(u *User) {\n\t\tu.ID = v\n\t}\n}"},
+		{"go-builder", "func NewUserBuilder() *UserBuilder {"},
+		{"go-builder", "func (b *UserBuilder) Nickname(v *string) *UserBuilder {\n\tb.value.Nickname = v\n\treturn b\n}"},
+		{"go-builder", "func (b *UserBuilder) Build() User {"},
+		{"go-clone", "import (\n\t\"maps\"\n\t\"slices\"\n)"},
+		{"go-clone", "\tif u == nil {\n\t\treturn nil\n\t}\n\tclone := *u\n"},
+		{"go-clone", "\tif clone.Nickname != nil {\n\t\tv1 := *clone.Nickname\n\t\tclone.Nickname = &v1\n\t}"},
+		{"go-clone", "\tclone.Address = *clone.Address.Clone()"},
+		{"go-clone", "\tclone.History = slices.Clone(clone.History)\n\tfor i1 := range clone.History {\n\t\tclone.History[i1] = clone.History[i1].Clone()\n\t}"},
+		{"go-clone", "\tclone.Scores = maps.Clone(clone.Scores)\n\tfor k1, v1 := range clone.Scores {\n\t\tv1 = slices.Clone(v1)\n\t\tclone.Scores[k1] = v1\n\t}"},
+	}
+
+	outputs := make(map[string]string)
+	for _, tt := range tests {
+		if _, ok := outputs[tt.template]; !ok {
+			outputs[tt.template] = generate(tt.template)
+		}
+		output := outputs[tt.template]
+		if !strings.Contains(output, tt.contains) {
+			t.Errorf("%s: expected output to contain %q\n\nGot:\n%s", tt.template, tt.contains, output)
+		}
+	}
+
+	if strings.Contains(outputs["go-clone"], "uuid") {
+		t.Errorf("expected unused imports to be dropped\n\nGot:\n%s", outputs["go-clone"])
+	}
+
+	// Custom templates writing to a .go file are formatted as well
+	templatePath := filepath.Join(tmpDir, "custom.tmpl")
+	customTemplate := "package {{ .File.Package }}\n{{ range .Types }}\nvar _ = fmt.Sprint(time.Second)   // {{ .Name }}\n{{ end }}"
+	if err := os.WriteFile(templatePath, []byte(customTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	gen := generator.New(config.New())
+	gen.SetOutput(filepath.Join(tmpDir, "out.go"))
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if want := "import (\n\t\"fmt\"\n\t\"time\"\n)\n\nvar _ = fmt.Sprint(time.Second) // Role\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected output to contain %q\n\nGot:\n%s", want, buf.String())
+	}
+}
+
+// TestE2E_OutputFormatting tests the formatting applied to output based on
+// the output file extension.
+func TestE2E_OutputFormatting(t *testing.T) {
+	inputContent := `package models
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	generate := func(cfg *config.Config, template, output string) (string, error) {
+		templatePath := filepath.Join(tmpDir, "template.tmpl")
+		if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+			t.Fatalf("failed to write template: %v", err)
+		}
+		gen := generator.New(cfg)
+		gen.SetOutput(output)
+		if err := gen.LoadTemplate(templatePath); err != nil {
+			t.Fatalf("failed to load template: %v", err)
+		}
+		var buf bytes.Buffer
+		err := gen.Generate(file, &buf)
+		return buf.String(), err
+	}
+
+	tests := []struct {
+		name     string
+		output   string
+		template string
+		want     string
+	}{
+		{
+			name:     "typescript whitespace",
+			output:   "types.ts",
+			template: "\n\n// Header   \n\n\n{{ range .Types }}export interface {{ .Name }} {\n\n{{ range .Fields }}\n  {{ jsonName . }}: {{ mapType .Type }};\n{{ end }}\n}\n\n\n{{ end }}\n\n",
+			want:     "// Header\n\nexport interface User {\n  name: string;\n\n  age: number;\n}\n",
+		},
+		{
+			name:     "whitespace-only line after blank line",
+			output:   "types.ts",
+			template: "export const a = 1;\n\n\u00a0\v\nexport const b = 2;\n",
+			want:     "export const a = 1;\n\n\u00a0\v\nexport const b = 2;\n",
+		},
+		{
+			name:     "json indentation",
+			output:   "types.json",
+			template: `{"types": [{{ range $i, $t := .Types }}{{ if $i }},{{ end }}{"name": "{{ $t.Name }}", "fields": {{ len $t.Fields }}}{{ end }}]}`,
+			want:     "{\n  \"types\": [\n    {\n      \"name\": \"User\",\n      \"fields\": 2\n    }\n  ]\n}\n",
+		},
+		{
+			name:     "yaml whitespace",
+			output:   "types.yaml",
+			template: "types:   \n\n\n{{ range .Types }}  - {{ .Name }}\n{{ end }}\n\n",
+			want:     "types:\n\n  - User\n",
+		},
+		{
+			name:     "go formatting",
+			output:   "types.go",
+			template: "package {{ .File.Package }}\n{{ range .Types }}\nfunc ( x {{ .Name }} )  String( ) string { return fmt.Sprint(x.Name) }\n{{ end }}",
+			want:     "package models\n\nimport \"fmt\"\n\nfunc (x User) String() string { return fmt.Sprint(x.Name) }\n",
+		},
+		{
+			name:     "unknown extension untouched",
+			output:   "types.txt",
+			template: "{{ range .Types }}{{ .Name }}   \n\n\n{{ end }}",
+			want:     "User   \n\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := generate(config.New(), tt.template, tt.output)
+			if err != nil {
+				t.Fatalf("failed to generate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected output\n\nWant:\n%q\n\nGot:\n%q", tt.want, got)
+			}
+		})
+	}
+
+	// Formatting failures point at the offending line
+	failures := []struct {
+		name     string
+		output   string
+		template string
+		want     string
+	}{
+		{"unbalanced typescript", "types.ts", "export interface A {\n  a: string;\n}\n}\n", "line 4: unexpected '}'\n\t4 | }"},
+		{"invalid json", "types.json", "{\n  \"a\": 1,\n  \"b\": \n}\n", "line 4:"},
+		{"invalid go", "types.go", "package models\n\nfunc broken( {\n}\n", "line 3:"},
+		{"invalid yaml", "types.yaml", "a: 1\n b: 2\n", "line 2:"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate(config.New(), tt.template, tt.output)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// External formatters run after the built-in formatting
+	if _, err := exec.LookPath("tr"); err == nil {
+		cfg := config.New()
+		cfg.Options.Formatters = map[string]string{".ts": "tr a-z A-Z"}
+		got, err := generate(cfg, "{{ range .Types }}export type {{ .Name }} = string;\n\n\n{{ end }}", "types.ts")
+		if err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		if want := "EXPORT TYPE USER = STRING;\n"; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+
+	// "none" disables formatting
+	cfg := config.New()
+	cfg.Options.Formatters = map[string]string{"ts": "none"}
+	got, err := generate(cfg, "{\n\n\n", "types.ts")
+	if err != nil || got != "{\n\n\n" {
+		t.Errorf("expected unformatted output, got %q (%v)", got, err)
+	}
+}
+
+// TestE2E_ProtectedRegions tests that hand-written code in protected
+// regions survives regeneration.
+func TestE2E_ProtectedRegions(t *testing.T) {
+	inputContent := `package models
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+	outputPath := filepath.Join(tmpDir, "types.ts")
+
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	generate := func(template, output string) (string, error) {
+		templatePath := filepath.Join(tmpDir, "template.tmpl")
+		if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+			t.Fatalf("failed to write template: %v", err)
+		}
+		gen := generator.New(config.New())
+		gen.SetOutput(output)
+		if err := gen.LoadTemplate(templatePath); err != nil {
+			t.Fatalf("failed to load template: %v", err)
+		}
+		var buf bytes.Buffer
+		err := gen.Generate(file, &buf)
+		return buf.String(), err
+	}
+
+	template := `{{ range .Types }}export interface {{ .Name }} {
+{{ range .Fields }}  {{ jsonName . }}: {{ mapType .Type }};
+{{ end }}}
+{{ end }}
+{{ region "custom" }}
+`
+
+	// First run: the region is empty
+	got, err := generate(template, outputPath)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if want := "export interface User {\n  name: string;\n}\n\n// gogen:begin custom\n// gogen:end custom\n"; got != want {
+		t.Fatalf("unexpected output\n\nWant:\n%s\n\nGot:\n%s", want, got)
+	}
+
+	// Hand-written code is added to the region, then the input changes
+	custom := strings.Replace(got, "// gogen:begin custom\n", "// gogen:begin custom\nexport function greet(u: User) {\n  return `hi ${u.name}`;\n}\n", 1)
+	if err := os.WriteFile(outputPath, []byte(custom), 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	file.Types[0].Fields = append(file.Types[0].Fields, model.Field{
+		Name:       "Age",
+		Type:       model.TypeRef{Kind: model.KindBasic, Name: "int"},
+		IsExported: true,
+	})
+
+	got, err = generate(template, outputPath)
+	if err != nil {
+		t.Fatalf("failed to regenerate: %v", err)
+	}
+	want := "export interface User {\n  name: string;\n  Age: number;\n}\n\n// gogen:begin custom\nexport function greet(u: User) {\n  return `hi ${u.name}`;\n}\n// gogen:end custom\n"
+	if got != want {
+		t.Errorf("unexpected output\n\nWant:\n%s\n\nGot:\n%s", want, got)
+	}
+
+	// Removing a region with content from the template is an error
+	if _, err := generate(`{{ range .Types }}{{ .Name }}{{ end }}`, outputPath); err == nil || !strings.Contains(err.Error(), "region custom is not declared") {
+		t.Errorf("expected lost region error, got %v", err)
+	}
+
+	// Regions can only be declared once
+	if _, err := generate(`{{ region "a" }}{{ region "a" }}`, outputPath); err == nil || !strings.Contains(err.Error(), `region "a" is declared twice`) {
+		t.Errorf("expected duplicate region error, got %v", err)
+	}
+
+	// The comment prefix follows the output language
+	pyPath := filepath.Join(tmpDir, "models.py")
+	if err := os.WriteFile(pyPath, []byte("# gogen:begin helpers\nX = 1\n# gogen:end helpers\n"), 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	got, err = generate(`{{ region "helpers" }}`, pyPath)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if want := "# gogen:begin helpers\nX = 1\n# gogen:end helpers"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Unterminated regions in the existing output are reported
+	if err := os.WriteFile(pyPath, []byte("# gogen:begin helpers\nX = 1\n"), 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	if _, err := generate(`{{ region "helpers" }}`, pyPath); err == nil || !strings.Contains(err.Error(), `models.py:1: region "helpers" is never closed`) {
+		t.Errorf("expected unclosed region error, got %v", err)
+	}
+
+	// With --per-type, each execution declares its regions again
+	perTypePath := filepath.Join(tmpDir, "per-type.ts")
+	if err := os.WriteFile(perTypePath, []byte("// gogen:begin User\nconst u = 1;\n// gogen:end User\n"), 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	templatePath := filepath.Join(tmpDir, "per-type.tmpl")
+	if err := os.WriteFile(templatePath, []byte("{{ region .Type.Name }}\n{{ region \"shared\" }}\n"), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	cfg := config.New()
+	cfg.Options.PerType = true
+	gen := generator.New(cfg)
+	gen.SetOutput(perTypePath)
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	perTypeFile := &model.File{Package: "models", Types: []model.Type{
+		{Name: "User", Kind: model.KindStruct, IsExported: true},
+		{Name: "Post", Kind: model.KindStruct, IsExported: true},
+	}}
+	var buf bytes.Buffer
+	if err := gen.Generate(perTypeFile, &buf); err != nil {
+		t.Fatalf("failed to generate per type: %v", err)
+	}
+	want = "// gogen:begin User\nconst u = 1;\n// gogen:end User\n// gogen:begin shared\n// gogen:end shared\n// gogen:begin Post\n// gogen:end Post\n// gogen:begin shared\n// gogen:end shared\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected per-type output\n\nWant:\n%s\n\nGot:\n%s", want, got)
+	}
+}
+
+// TestE2E_GenerationCache tests the cache used to skip unchanged targets and
+// the write-if-changed output.
+func TestE2E_GenerationCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "types.ts")
+
+	c, err := cache.Open(filepath.Join(tmpDir, "cache"))
+	if err != nil {
+		t.Fatalf("failed to open cache: %v", err)
+	}
+
+	key := cache.Key([]byte("v1"), []byte("package models"), []byte("{{ . }}"))
+	if key == cache.Key([]byte("v1package models"), []byte("{{ . }}")) {
+		t.Error("expected key parts to be delimited")
+	}
+	if c.Fresh(outputPath, key) {
+		t.Error("expected missing output to be stale")
+	}
+
+	content := []byte("export type User = string;\n")
+	written, err := cache.WriteFile(outputPath, content)
+	if err != nil || !written {
+		t.Fatalf("expected output to be written, got %v, %v", written, err)
+	}
+	if err := c.Store(outputPath, key, content); err != nil {
+		t.Fatalf("failed to store cache entry: %v", err)
+	}
+	if !c.Fresh(outputPath, key) {
+		t.Error("expected output to be fresh")
+	}
+	if c.Fresh(outputPath, cache.Key([]byte("v2"), []byte("package models"), []byte("{{ . }}"))) {
+		t.Error("expected output to be stale after a dependency changed")
+	}
+
+	// Unchanged content is not rewritten, keeping the modification time
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(outputPath, old, old); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+	written, err = cache.WriteFile(outputPath, content)
+	if err != nil || written {
+		t.Errorf("expected unchanged output not to be written, got %v, %v", written, err)
+	}
+	if info, err := os.Stat(outputPath); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("expected modification time to be kept")
+	}
+
+	// Outputs edited by hand (or deleted) are regenerated
+	if err := os.WriteFile(outputPath, []byte("edited\n"), 0644); err != nil {
+		t.Fatalf("failed to edit output: %v", err)
+	}
+	if c.Fresh(outputPath, key) {
+		t.Error("expected edited output to be stale")
+	}
+	if err := os.Remove(outputPath); err != nil {
+		t.Fatalf("failed to remove output: %v", err)
+	}
+	if c.Fresh(outputPath, key) {
+		t.Error("expected removed output to be stale")
+	}
+}
+
+// TestE2E_ConfigDiscovery tests finding .gogen.yaml in parent directories
+// and loading its targets.
+func TestE2E_ConfigDiscovery(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := filepath.Join(tmpDir, "internal", "models")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+
+	path, err := config.Find(nested)
+	if err != nil {
+		t.Fatalf("failed to look for config: %v", err)
+	}
+	if path != "" && strings.HasPrefix(path, tmpDir) {
+		t.Errorf("expected no config file, found %s", path)
+	}
+
+	configContent := `options:
+  tagKey: db
+targets:
+  - name: api
+    input: models.go
+    template: typescript
+    output: web/models.ts
+    types: [User, Product]
+  - name: db
+    input: models.go
+    template: sql-postgres
+    output: schema.sql
+    exclude: [Internal]
+`
+	configPath := filepath.Join(tmpDir, config.FileName)
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	path, err = config.Find(nested)
+	if err != nil {
+		t.Fatalf("failed to find config: %v", err)
+	}
+	if path != configPath {
+		t.Fatalf("expected config %s, got %s", configPath, path)
+	}
+
+	cfg := config.New()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Options.TagKey != "db" {
+		t.Errorf("expected tag key db, got %q", cfg.Options.TagKey)
+	}
+	if len(cfg.Targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(cfg.Targets))
+	}
+
+	api := cfg.Targets[0]
+	if api.Name != "api" || api.Template != "typescript" {
+		t.Errorf("unexpected target: %+v", api)
+	}
+	// Paths are relative to the config file
+	if api.Input != filepath.Join(tmpDir, "models.go") || api.Output != filepath.Join(tmpDir, "web", "models.ts") {
+		t.Errorf("expected paths relative to the config file, got %s and %s", api.Input, api.Output)
+	}
+	if strings.Join(api.Types, ",") != "User,Product" {
+		t.Errorf("expected target types User,Product, got %v", api.Types)
+	}
+	if db := cfg.Targets[1]; strings.Join(db.Exclude, ",") != "Internal" {
+		t.Errorf("expected target exclude Internal, got %v", db.Exclude)
+	}
+}
+
+// TestE2E_GoGenerateMode tests parsing a whole package and recording the
+// command line in generated headers, as used from //go:generate.
+func TestE2E_GoGenerateMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"user.go": `package models
+
+// User is a user account.
+type User struct {
+	ID     string ` + "`json:\"id\"`" + `
+	Status Status ` + "`json:\"status\"`" + `
+}
+`,
+		"status.go": `package models
+
+// Status is the status of an account.
+type Status string
+
+const (
+	StatusActive Status = "active"
+	StatusBanned Status = "banned"
+)
+`,
+		"user_builder.go": `// Code generated by gogen. DO NOT EDIT.
+
+package models
+
+type UserBuilder struct{}
+`,
+		"user_test.go": `package models
+
+type testFixture struct{}
+`,
+		"ignored.go": `//go:build ignore
+
+package main
+
+type Tool struct{}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	file, err := parser.New().ParseDir(tmpDir, "models")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	if file.Package != "models" {
+		t.Errorf("expected package models, got %q", file.Package)
+	}
+
+	var names []string
+	for _, typ := range file.Types {
+		names = append(names, typ.Name)
+	}
+	// Files are read in name order; generated, test and ignored files are skipped
+	if strings.Join(names, ",") != "Status,User" {
+		t.Fatalf("expected types Status,User, got %v", names)
+	}
+	if len(file.Types[0].Constants) != 2 {
+		t.Errorf("expected constants of Status to be attached, got %v", file.Types[0].Constants)
+	}
+
+	if _, err := parser.New().ParseDir(t.TempDir(), ""); err == nil {
+		t.Error("expected error for a directory without Go files")
+	}
+
+	cfg := config.New()
+	gen := generator.New(cfg)
+	gen.SetCommand("gogen generate -t typescript -o models.ts")
+	if err := gen.LoadTemplate("typescript"); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "// Source: "+tmpDir+"\n// Command: gogen generate -t typescript -o models.ts\n") {
+		t.Errorf("expected header to record the command line, got:\n%s", output)
+	}
+	if !strings.Contains(output, "export interface User") {
+		t.Errorf("expected User interface, got:\n%s", output)
+	}
+
+	// Without a command, the header is unchanged
+	gen = generator.New(cfg)
+	if err := gen.LoadTemplate("typescript"); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	buf.Reset()
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if strings.Contains(buf.String(), "Command:") {
+		t.Errorf("expected no command line in header, got:\n%s", buf.String())
+	}
+}
+
+// TestE2E_ParseSource tests parsing Go source from memory, as read from stdin.
+func TestE2E_ParseSource(t *testing.T) {
+	src := []byte(`package models
+
+// Item is an item in stock.
+type Item struct {
+	SKU   string ` + "`json:\"sku\"`" + `
+	Count int    ` + "`json:\"count\"`" + `
+}
+`)
+
+	file, err := parser.New().ParseSource("<stdin>", src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	if file.Path != "<stdin>" || file.Package != "models" {
+		t.Errorf("unexpected file %s (package %s)", file.Path, file.Package)
+	}
+	if len(file.Types) != 1 || file.Types[0].Name != "Item" {
+		t.Fatalf("expected type Item, got %v", file.Types)
+	}
+	if pos := file.Types[0].Pos; pos.File != "<stdin>" || pos.Line != 4 {
+		t.Errorf("expected position <stdin>:4, got %s", pos)
+	}
+
+	cfg := config.New()
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate("typescript"); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if !strings.Contains(buf.String(), "// Source: <stdin>") || !strings.Contains(buf.String(), "sku: string") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	_, err = parser.New().ParseSource("<stdin>", []byte("package models\n\ntype Broken struct {\n"))
+	if err == nil || !strings.Contains(err.Error(), "<stdin>:") {
+		t.Errorf("expected syntax error with a stdin position, got %v", err)
+	}
+}
+
+// TestE2E_ParallelParsing tests sharing a parser and a parse cache between
+// concurrent targets.
+func TestE2E_ParallelParsing(t *testing.T) {
+	tmpDir := t.TempDir()
+	// The same package name resolves to different import paths per file
+	sources := map[string]string{
+		"a.go": `package models
+
+import "example.com/a/id"
+
+type A struct {
+	ID id.ID ` + "`json:\"id\"`" + `
+}
+`,
+		"b.go": `package models
+
+import "example.com/b/id"
+
+type B struct {
+	ID id.ID ` + "`json:\"id\"`" + `
+}
+`,
+	}
+	for name, content := range sources {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	p := parser.New()
+	cache := parser.NewCache()
+	var parses atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		for name, want := range map[string]string{"a.go": "example.com/a/id.ID", "b.go": "example.com/b/id.ID"} {
+			name, want := name, want
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				file, err := cache.Parse(name, func() (*model.File, error) {
+					parses.Add(1)
+					return p.ParseFile(filepath.Join(tmpDir, name))
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if got := file.Types[0].Fields[0].Type.QualifiedName(); got != want {
+					errs <- fmt.Errorf("%s: expected %s, got %s", name, want, got)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := parses.Load(); n != 2 {
+		t.Errorf("expected each input to be parsed once, got %d parses", n)
+	}
+
+	// Errors are cached like results
+	_, err1 := cache.Parse("missing.go", func() (*model.File, error) {
+		return p.ParseFile(filepath.Join(tmpDir, "missing.go"))
+	})
+	_, err2 := cache.Parse("missing.go", func() (*model.File, error) {
+		t.Error("expected failed parse not to be retried")
+		return nil, nil
+	})
+	if err1 == nil || err1 != err2 {
+		t.Errorf("expected the same cached error, got %v and %v", err1, err2)
+	}
+}
+
+// TestE2E_Diagnostics tests warnings about unsupported constructs and the
+// conversion of errors into diagnostics.
+func TestE2E_Diagnostics(t *testing.T) {
+	src := []byte(`package models
+
+type Page[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+}
+
+type Job struct {
+	ID       string            ` + "`json:\"id\"`" + `
+	Done     chan struct{}     ` + "`json:\"done\"`" + `
+	OnFinish func(error)       ` + "`json:\"-\"`" + `
+	Hooks    map[string]func() ` + "`json:\"hooks\"`" + `
+	Page     Page[Job]         ` + "`json:\"page\"`" + `
+	internal chan int
+}
+`)
+
+	diags := &diag.Collector{}
+	file, err := parser.New(parser.WithDiagnostics(diags)).ParseSource("jobs.go", src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	if len(file.Types) != 2 {
+		t.Fatalf("expected 2 types, got %d", len(file.Types))
+	}
+
+	var got []string
+	for _, d := range diags.Diagnostics() {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"jobs.go:9:2: warning: field Done has unsupported type chan struct{} [unsupported-type]",
+		"jobs.go:11:2: warning: field Hooks has unsupported type map[string]func() [unsupported-type]",
+		"jobs.go:12:11: warning: unsupported type expression Page[Job], treated as unknown [unknown-type]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if diags.Count(diag.Warning) != 3 || diags.Count(diag.Error) != 0 {
+		t.Errorf("expected 3 warnings and no errors, got %d and %d", diags.Count(diag.Warning), diags.Count(diag.Error))
+	}
+
+	// Only the tag key naming fields skips them: db:"-" fields are still
+	// serialized, and checked
+	skipped := []byte(`package models
+
+type Task struct {
+	Stop   chan int ` + "`json:\"stop\" db:\"-\"`" + `
+	Notify chan int ` + "`json:\"-\" yaml:\"notify\"`" + `
+	Cancel chan int ` + "`yaml:\"-\"`" + `
+}
+`)
+	for key, want := range map[string]string{"json": "Stop Cancel", "yaml": "Stop Notify"} {
+		diags := &diag.Collector{}
+		file, err := parser.New(parser.WithDiagnostics(diags), parser.WithTagKey(key)).ParseSource("task.go", skipped)
+		if err != nil {
+			t.Fatalf("failed to parse source: %v", err)
+		}
+		var warned []string
+		for _, d := range diags.Diagnostics() {
+			warned = append(warned, strings.Fields(d.Message)[1])
+		}
+		if got := strings.Join(warned, " "); got != want {
+			t.Errorf("tag key %s: expected warnings for %s, got %s", key, want, got)
+		}
+
+		// Templates skip the same fields
+		cfg := config.New()
+		cfg.Options.TagKey = key
+		gen := generator.New(cfg)
+		if err := gen.LoadTemplate("pydantic"); err != nil {
+			t.Fatalf("failed to load template: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		for _, name := range []string{"stop", "notify", "cancel"} {
+			kept := strings.Contains(buf.String(), name+":") || strings.Contains(buf.String(), "alias=\""+name+"\"")
+			if kept != strings.Contains(strings.ToLower(want), name) {
+				t.Errorf("tag key %s: unexpected %s field in output:\n%s", key, name, buf.String())
+			}
+		}
+	}
+
+	// Syntax errors keep their positions
+	_, err = parser.New().ParseSource("broken.go", []byte("package models\n\ntype X struct {\n\tA )\n}\n"))
+	syntax := diag.FromError(fmt.Errorf("parsing input: %w", err))
+	if len(syntax) == 0 || syntax[0].Code != diag.CodeSyntax || syntax[0].Pos.Line != 4 || syntax[0].Severity != diag.Error {
+		t.Errorf("expected syntax error at line 4, got %v", syntax)
+	}
+
+	// Joined errors become one diagnostic each, in order
+	joined := diag.FromError(errors.Join(errors.New("first"), errors.New("second")))
+	if len(joined) != 2 || joined[0].Message != "first" || joined[1].String() != "error: second" {
+		t.Errorf("unexpected diagnostics for joined errors: %v", joined)
+	}
+
+	// Template failures point at the type being generated
+	templatePath := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(templatePath, []byte(`{{ range .Types }}{{ if eq .Name "Job" }}{{ .Missing }}{{ end }}{{ end }}`), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	gen := generator.New(config.New())
+	if err := gen.LoadTemplate(templatePath); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	err = gen.Generate(file, io.Discard)
+	var d *diag.Diagnostic
+	if !errors.As(err, &d) || d.Code != diag.CodeTemplate || d.Pos.Line != 7 {
+		t.Fatalf("expected template diagnostic at Job (line 7), got %v", err)
+	}
+
+	data, err := diag.JSON([]*diag.Diagnostic{d})
+	if err != nil {
+		t.Fatalf("failed to encode diagnostics: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode diagnostics: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0]["file"] != "jobs.go" || decoded[0]["line"] != float64(7) ||
+		decoded[0]["severity"] != "error" || decoded[0]["code"] != "template" {
+		t.Errorf("unexpected JSON diagnostics: %s", data)
+	}
+}
+
+// TestE2E_TemplateLint tests checking templates against the model without
+// executing them.
+func TestE2E_TemplateLint(t *testing.T) {
+	gen := generator.New(config.New())
+
+	// Built-in templates are clean
+	for _, name := range templates.Names() {
+		diags, err := gen.LintTemplate(name)
+		if err != nil {
+			t.Fatalf("failed to lint %s: %v", name, err)
+		}
+		for _, d := range diags {
+			t.Errorf("unexpected diagnostic in built-in template: %s", d)
+		}
+	}
+
+	templateContent := `{{- range .Types }}
+{{ .Name }} {{ .Feilds }}
+{{- range $i, $f := .Fields }}{{ $f.Type.QualifiedName 1 }}{{ mapTyp $f.Type }}{{ end }}
+{{- template "row" . }}
+{{- end }}
+{{ .Config.Options.TagKey | printf "%s" }}{{ eq 1 }}
+{{- define "row" }}{{ .Doc }}{{ .Dco }}{{ end }}
+{{- define "unused" }}{{ end }}
+`
+	templatePath := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	diags, err := gen.LintTemplate(templatePath)
+	if err != nil {
+		t.Fatalf("failed to lint template: %v", err)
+	}
+
+	var got []string
+	for _, d := range diags {
+		got = append(got, fmt.Sprintf("%d %s %s", d.Pos.Line, d.Severity, d.Code))
+	}
+	want := []string{
+		"2 error unknown-field",    // .Feilds
+		"3 error arity",            // QualifiedName takes no arguments
+		"3 error unknown-function", // mapTyp
+		"6 error arity",            // eq needs two arguments
+		"7 error unknown-field",    // .Dco, checked with the dot passed by {{ template }}
+		"8 warning unused-define",  // unused
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(diags) > 0 && !strings.Contains(diags[0].Message, "model.Type has no field or method Feilds") {
+		t.Errorf("unexpected message: %s", diags[0].Message)
+	}
+
+	// Syntax errors are reported as diagnostics
+	if err := os.WriteFile(templatePath, []byte("{{ range .Types }\n"), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	diags, err = gen.LintTemplate(templatePath)
+	if err != nil {
+		t.Fatalf("failed to lint template: %v", err)
+	}
+	if len(diags) != 1 || diags[0].Code != diag.CodeSyntax || diags[0].Pos.Line != 1 {
+		t.Errorf("expected a syntax error on line 1, got %v", diags)
+	}
+}
+
+// TestE2E_GoldenHarness tests golden-file test cases: creating expected
+// files with update, passing, and reporting differences as a diff.
+func TestE2E_GoldenHarness(t *testing.T) {
+	dir := t.TempDir()
+	inputs := map[string]string{
+		"users": `package models
+
+type User struct {
+	ID   int    ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+		"orders": `package models
+
+type Order struct {
+	Total float64 ` + "`json:\"total\"`" + `
+}
+`,
+	}
+	for name, src := range inputs {
+		caseDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(caseDir, 0755); err != nil {
+			t.Fatalf("failed to create case: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(caseDir, golden.InputFile), []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write input: %v", err)
+		}
+	}
+	// Directories without an input are not cases
+	if err := os.MkdirAll(filepath.Join(dir, "shared"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	cases, err := golden.Discover(dir)
+	if err != nil {
+		t.Fatalf("failed to discover cases: %v", err)
+	}
+	if len(cases) != 2 || cases[0].Name != "orders" || cases[1].Name != "users" {
+		t.Fatalf("unexpected cases: %v", cases)
+	}
+
+	for _, c := range cases {
+		if err := c.Check("typescript", false); err == nil || !strings.Contains(err.Error(), "-update") {
+			t.Errorf("expected a missing expected file error, got %v", err)
+		}
+		if err := c.Check("typescript", true); err != nil {
+			t.Fatalf("failed to update %s: %v", c.Name, err)
+		}
+		if err := c.Check("typescript", false); err != nil {
+			t.Errorf("expected %s to pass after update: %v", c.Name, err)
+		}
+	}
+	expected := filepath.Join(dir, "users", "expected.ts")
+	content, err := os.ReadFile(expected)
+	if err != nil {
+		t.Fatalf("expected file not created: %v", err)
+	}
+	if !strings.Contains(string(content), "name: string") {
+		t.Errorf("unexpected expected file:\n%s", content)
+	}
+
+	gogentest.Run(t, dir, "typescript", false)
+
+	// A changed expected file is reported with a diff
+	edited := strings.Replace(string(content), "name: string", "fullName: string", 1)
+	if err := os.WriteFile(expected, []byte(edited), 0644); err != nil {
+		t.Fatalf("failed to edit expected file: %v", err)
+	}
+	err = cases[1].Check("typescript", false)
+	var mismatch *golden.Mismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a mismatch, got %v", err)
+	}
+	if !strings.Contains(mismatch.Diff, "-  fullName: string") || !strings.Contains(mismatch.Diff, "+  name: string") {
+		t.Errorf("unexpected diff:\n%s", mismatch.Diff)
+	}
+	if strings.Index(mismatch.Diff, "-  fullName") > strings.Index(mismatch.Diff, "+  name") {
+		t.Errorf("expected deletions before insertions:\n%s", mismatch.Diff)
+	}
+}
+
+// TestE2E_FieldFlags tests that nullable, optional and readonly fields are
+// told apart in the model and rendered precisely by the TypeScript, Zod and
+// Valibot templates.
+func TestE2E_FieldFlags(t *testing.T) {
+	inputContent := `package models
+
+type Account struct {
+	ID       string  ` + "`json:\"id\"`" + `
+	Email    *string ` + "`json:\"email\"`" + `
+	Nickname string  ` + "`json:\"nickname,omitempty\"`" + `
+	Avatar   *string ` + "`json:\"avatar,omitzero\"`" + `
+	//gogen:readonly
+	Created string ` + "`json:\"created\"`" + `
+	Note    string ` + "`json:\"note\"`" + ` //gogen:nullable
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	var flags []string
+	for _, f := range file.Types[0].Fields {
+		flags = append(flags, fmt.Sprintf("%s %t %t %t", f.Name, f.Nullable, f.Optional, f.Readonly))
+	}
+	want := []string{
+		"ID false false false",
+		"Email true false false",
+		"Nickname false true false",
+		"Avatar true true false",
+		"Created false false true",
+		"Note true false false",
+	}
+	if strings.Join(flags, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected flags (nullable optional readonly):\n%s\nwant:\n%s", strings.Join(flags, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Config entries set flags too
+	cfg := config.New()
+	cfg.Options.Fields = map[string][]string{"ID": {"readonly"}, "Account.Note": {"optional"}}
+
+	tests := map[string][]string{
+		"typescript": {
+			"readonly id: string;",
+			"  email: string | null;",
+			"nickname?: string;",
+			"avatar?: string | null;",
+			"readonly created: string;",
+			"note?: string | null;",
+		},
+		"zod": {
+			"email: z.string().nullable(),",
+			"nickname: z.string().optional(),",
+			"avatar: z.string().nullish(),",
+			"note: z.string().nullish(),",
+		},
+		"valibot": {
+			"email: v.nullable(v.string()),",
+			"nickname: v.optional(v.string()),",
+			"avatar: v.nullish(v.string()),",
+		},
+	}
+	for name, wants := range tests {
+		gen := generator.New(cfg)
+		if err := gen.LoadTemplate(name); err != nil {
+			t.Fatalf("failed to load %s: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate %s: %v", name, err)
+		}
+		for _, w := range wants {
+			if !strings.Contains(buf.String(), w) {
+				t.Errorf("%s: expected %q in output:\n%s", name, w, buf.String())
+			}
+		}
+	}
+	if file.Types[0].Fields[0].Readonly {
+		t.Error("config flags should not modify the parsed file")
+	}
+
+	// Unknown flags are errors
+	cfg.Options.Fields = map[string][]string{"ID": {"immutable"}}
+	gen := generator.New(cfg)
+	if err := gen.LoadTemplate("typescript"); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	if err := gen.Generate(file, io.Discard); err == nil || !strings.Contains(err.Error(), `unknown flag "immutable"`) {
+		t.Errorf("expected an unknown flag error, got %v", err)
+	}
+}
+
+// TestE2E_Marshalers tests that types whose marshaling methods replace
+// their declared shape are detected and generated as their serialized
+// shape.
+func TestE2E_Marshalers(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"money.go": `package shop
+
+import "github.com/shopspring/decimal"
+
+type Money struct {
+	Amount   decimal.Decimal
+	Currency string
+}
+
+type Payload struct {
+	Data []byte
+}
+
+//gogen:marshal struct
+type Point struct {
+	X int ` + "`json:\"x\"`" + `
+}
+
+type Level int
+
+const (
+	Low Level = iota
+	High
+)
+
+//gogen:marshal number
+type Ratio struct {
+	Num, Den int
+}
+
+type Order struct {
+	Total   Money   ` + "`json:\"total\"`" + `
+	Payload Payload ` + "`json:\"payload\"`" + `
+	At      Point   ` + "`json:\"at\"`" + `
+	Level   Level   ` + "`json:\"level\"`" + `
+	Ratio   Ratio   ` + "`json:\"ratio\"`" + `
+}
+`,
+		// Methods are found in the other files of the package
+		"methods.go": `package shop
+
+import "encoding/json"
+
+func (m Money) MarshalText() ([]byte, error) { return []byte(m.Currency), nil }
+
+func (p *Payload) MarshalJSON() ([]byte, error) { return json.Marshal(p.Data) }
+
+func (p Point) String() string { return "" }
+
+func (l Level) String() string { return "" }
+`,
+		// Methods of imported types are unknown, and not assumed
+		"row.go": `package shop
+
+import "database/sql"
+
+type Row struct {
+	sql.NullString
+	X int ` + "`json:\"x\"`" + `
+}
+
+// Stringers keep their shape, with a warning
+type Label struct {
+	Text string ` + "`json:\"text\"`" + `
+}
+
+func (l Label) String() string { return l.Text }
+`,
+		// SQL outputs describe storage, not JSON
+		"store.go": `package shop
+
+import "encoding/json"
+
+type Account struct {
+	ID   int64  ` + "`db:\"id\"`" + `
+	Name string ` + "`db:\"name\"`" + `
+}
+
+func (a Account) MarshalJSON() ([]byte, error) { return json.Marshal(a.Name) }
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	diags := &diag.Collector{}
+	file, err := parser.New(parser.WithDiagnostics(diags)).ParseDir(tmpDir, "")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	var got []string
+	for _, typ := range file.Types {
+		shape, err := generator.MarshalShape(typ)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", typ.Name, err)
+		}
+		got = append(got, fmt.Sprintf("%s %q %q", typ.Name, typ.Marshaler, shape))
+	}
+	want := []string{
+		`Money "encoding.TextMarshaler" "string"`,
+		`Payload "json.Marshaler" "unknown"`, // Pointer receiver
+		`Point "fmt.Stringer" ""`,            // Directive keeps the struct
+		`Level "fmt.Stringer" ""`,            // Enums keep their constants
+		`Ratio "" "number"`,
+		`Order "" ""`,
+		`Row "" ""`,
+		`Label "fmt.Stringer" ""`,
+		`Account "json.Marshaler" "unknown"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected marshalers:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	generate := func(template string) string {
+		gen := generator.New(config.New())
+		gen.SetDiagnostics(diags)
+		if err := gen.LoadTemplate(template); err != nil {
+			t.Fatalf("failed to load %s: %v", template, err)
+		}
+		var buf bytes.Buffer
+		if err := gen.Generate(file, &buf); err != nil {
+			t.Fatalf("failed to generate %s: %v", template, err)
+		}
+		return buf.String()
+	}
+
+	ts := generate("typescript")
+	for _, w := range []string{
+		"export type Money = string;",
+		"export interface Label {\n  text: string;",
+		"export type Payload = unknown;",
+		"export interface Point {\n  x: number;",
+		"export type Ratio = number;",
+		"total: Money;",
+	} {
+		if !strings.Contains(ts, w) {
+			t.Errorf("expected %q in TypeScript output:\n%s", w, ts)
+		}
+	}
+	if zod := generate("zod"); !strings.Contains(zod, "export const MoneySchema = z.string();") {
+		t.Errorf("expected Money as a string schema:\n%s", zod)
+	}
+	var warned []string
+	for _, d := range diags.Diagnostics() {
+		warned = append(warned, fmt.Sprintf("%s:%d %s", filepath.Base(d.Pos.File), d.Pos.Line, d.Code))
+	}
+	// Row may marshal through sql.NullString, which is not loaded
+	if got := strings.Join(warned, ", "); got != "row.go:6 marshaler, row.go:11 marshaler" {
+		t.Errorf("expected marshaler warnings for Row and Label only, got %s", got)
+	}
+
+	// Go outputs describe the Go types, and keep their fields
+	if clone := generate("go-clone"); !strings.Contains(clone, "func (m *Money) Clone()") {
+		t.Errorf("expected Money to keep its struct shape in Go output:\n%s", clone)
+	}
+	gen := generator.New(config.New())
+	gen.SetOutput("schema.sql")
+	if err := gen.LoadTemplate("sql-postgres"); err != nil {
+		t.Fatalf("failed to load sql-postgres: %v", err)
+	}
+	var sql bytes.Buffer
+	if err := gen.Generate(file, &sql); err != nil {
+		t.Fatalf("failed to generate SQL: %v", err)
+	}
+	if !strings.Contains(sql.String(), "CREATE TABLE IF NOT EXISTS accounts (\n  id BIGINT NOT NULL,") {
+		t.Errorf("expected Account to keep its table in SQL output:\n%s", sql.String())
+	}
+
+	// Unknown shapes are reported at the type
+	file.Types[0].Directives = model.Directives{"marshal": "date"}
+	_, err = generator.MarshalShape(file.Types[0])
+	var d *diag.Diagnostic
+	if !errors.As(err, &d) || d.Code != diag.CodeDirective || d.Pos.Line != 5 {
+		t.Errorf("expected a directive diagnostic on line 5, got %v", err)
+	}
+}
+
+// TestE2E_Variants tests deriving create and update inputs from structs,
+// with readonly fields left out of inputs and write-only fields left out of
+// responses.
+func TestE2E_Variants(t *testing.T) {
+	inputContent := `package models
+
+// User is a user account.
+type User struct {
+	//gogen:readonly
+	ID    string  ` + "`json:\"id\"`" + `
+	Name  string  ` + "`json:\"name\"`" + `
+	Email *string ` + "`json:\"email\"`" + `
+	//gogen:writeonly
+	Password  string ` + "`json:\"password\"`" + `
+	CreatedAt string ` + "`json:\"createdAt\"`" + `
+}
+
+//gogen:variants none
+type Session struct {
+	Token string ` + "`json:\"token\"`" + `
+}
+
+//gogen:variants update
+type Settings struct {
+	Theme string ` + "`json:\"theme\"`" + `
+}
+`
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.go")
+	if err := os.WriteFile(inputPath, []byte(inputContent), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+	file, err := parser.New().ParseFile(inputPath)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	if f := file.Types[0].Fields[3]; !f.WriteOnly || f.Readonly {
+		t.Errorf("expected Password to be write-only, got %+v", f)
+	}
+
+	generate := func(cfg *config.Config, template string) (string, error) {
+		gen := generator.New(cfg)
+		if err := gen.LoadTemplate(template); err != nil {
+			t.Fatalf("failed to load %s: %v", template, err)
+		}
+		var buf bytes.Buffer
+		err := gen.Generate(file, &buf)
+		return buf.String(), err
+	}
+
+	cfg := config.New()
+	cfg.Options.Variants = []string{"create", "update"}
+	cfg.Options.Fields = map[string][]string{"CreatedAt": {"readonly"}}
+	got, err := generate(cfg, "typescript")
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	want := `export interface User {
+  readonly id: string;
+  name: string;
+  email: string | null;
+  readonly createdAt: string;
+}
+
+/** CreateUserInput is the input to create a User. */
+export interface CreateUserInput {
+  name: string;
+  email: string | null;
+  password: string;
+}
+
+/** UpdateUserInput is the input to update a User. All fields are optional. */
+export interface UpdateUserInput {
+  name?: string;
+  email?: string | null;
+  password?: string;
+}
+
+export interface Session {
+  token: string;
+}
+
+export interface Settings {
+  theme: string;
+}
+
+/** UpdateSettingsInput is the input to update a Settings. All fields are optional. */
+export interface UpdateSettingsInput {
+  theme?: string;
+}
+`
+	if !strings.Contains(got, want) {
+		t.Errorf("unexpected output\n\nWant:\n%s\n\nGot:\n%s", want, got)
+	}
+
+	// Schemas are derived too, but Go outputs describe the Go types only
+	got, err = generate(cfg, "zod")
+	if err != nil {
+		t.Fatalf("failed to generate zod: %v", err)
+	}
+	if !strings.Contains(got, "export const UpdateUserInputSchema = z.object({\n  name: z.string().optional(),\n  email: z.string().nullish(),") {
+		t.Errorf("expected an update schema with optional fields:\n%s", got)
+	}
+	got, err = generate(cfg, "go-clone")
+	if err != nil {
+		t.Fatalf("failed to generate Go: %v", err)
+	}
+	if strings.Contains(got, "CreateUserInput") {
+		t.Errorf("Go outputs should not derive variants:\n%s", got)
+	}
+
+	// Write-only fields are left out of responses without variants too,
+	// but kept in Go outputs, which describe the Go types
+	cfg.Options.Variants = nil
+	got, err = generate(cfg, "typescript")
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if strings.Contains(got, "password") || strings.Contains(got, "CreateUserInput") {
+		t.Errorf("expected no password field and no variants without options.variants:\n%s", got)
+	}
+	got, err = generate(cfg, "go-options")
+	if err != nil {
+		t.Fatalf("failed to generate Go: %v", err)
+	}
+	if !strings.Contains(got, "Password") {
+		t.Errorf("expected Go output to keep the Password field:\n%s", got)
+	}
+
+	// Unknown variants are errors
+	cfg.Options.Variants = []string{"patch"}
+	if _, err := generate(cfg, "typescript"); err == nil || !strings.Contains(err.Error(), `unknown variant "patch"`) {
+		t.Errorf("expected an unknown variant error, got %v", err)
+	}
+
+	// SQL written to stdout describes the stored columns too, like a .sql file
+	bin := buildGogen(t)
+	accounts := `package models
+
+type Account struct {
+	ID int64 ` + "`db:\"id\"`" + `
+	//gogen:writeonly
+	Password string ` + "`db:\"password\"`" + `
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "accounts.go"), []byte(accounts), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, config.FileName), []byte("options:\n  variants: [create, update]\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	stdout, stderr, err := runGogen(t, bin, tmpDir, "generate", "-i", "accounts.go", "-t", "sql-postgres")
+	if err != nil {
+		t.Fatalf("gogen generate failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "password TEXT NOT NULL") || strings.Contains(stdout, "_input") {
+		t.Errorf("expected the accounts table with its password column and no variants:\n%s", stdout)
+	}
+}
+
+// buildGogen builds the gogen command for tests that run it.
+func buildGogen(t *testing.T) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "gogen")
+	if out, err := exec.Command("go", "build", "-o", bin, "./cmd/gogen").CombinedOutput(); err != nil {
+		t.Fatalf("failed to build gogen: %v\n%s", err, out)
+	}
+	return bin
+}
+
+// runGogen runs the gogen command in dir, returning its stdout and stderr.
+func runGogen(t *testing.T, bin, dir string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	err = cmd.Run()
+	return out.String(), errOut.String(), err
+}
+
+// TestE2E_PackageCache tests that the cache of a package directory target
+// ignores test files and the target's own output.
+func TestE2E_PackageCache(t *testing.T) {
+	bin := buildGogen(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"user.go": `package models
+
+type User struct {
+	Tags []string ` + "`json:\"tags\"`" + `
+}
+`,
+		"user_test.go": `package models
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	args := []string{"generate", "-v", "--cache-dir", t.TempDir(), "-i", ".", "-t", "go-clone", "-o", "user_clone.go"}
+	generate := func() string {
+		t.Helper()
+		_, stderr, err := runGogen(t, bin, dir, args...)
+		if err != nil {
+			t.Fatalf("gogen generate failed: %v\n%s", err, stderr)
+		}
+		return stderr
+	}
+
+	if out := generate(); !strings.Contains(out, "Generated output to user_clone.go") {
+		t.Fatalf("expected output to be generated, got:\n%s", out)
+	}
+	if out := generate(); !strings.Contains(out, "user_clone.go is up to date") {
+		t.Errorf("expected a cache hit after writing the output, got:\n%s", out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user_test.go"), []byte("package models\n\nvar _ = 1\n"), 0644); err != nil {
+		t.Fatalf("failed to edit test file: %v", err)
+	}
+	if out := generate(); !strings.Contains(out, "user_clone.go is up to date") {
+		t.Errorf("expected a cache hit after editing a test file, got:\n%s", out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(files["user.go"]+"\ntype Team struct{}\n"), 0644); err != nil {
+		t.Fatalf("failed to edit input: %v", err)
+	}
+	if out := generate(); strings.Contains(out, "up to date") {
+		t.Errorf("expected an input change to regenerate, got:\n%s", out)
+	}
+}
+
+// TestE2E_Archive tests bundling the outputs of targets into tar and zip
+// streams on stdout.
+func TestE2E_Archive(t *testing.T) {
+	bin := buildGogen(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"models.go": `package models
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+		config.FileName: `targets:
+  - name: ts
+    input: models.go
+    template: typescript
+    output: web/models.ts
+  - name: py
+    input: models.go
+    template: pydantic
+    output: ./api/models.py
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	archive := func(format string) []byte {
+		t.Helper()
+		stdout, stderr, err := runGogen(t, bin, dir, "generate", "--archive", format)
+		if err != nil {
+			t.Fatalf("gogen generate --archive %s failed: %v\n%s", format, err, stderr)
+		}
+		return []byte(stdout)
+	}
+
+	for _, format := range []string{"tar", "zip"} {
+		data := archive(format)
+		if again := archive(format); !bytes.Equal(data, again) {
+			t.Errorf("%s archive differs between runs", format)
+		}
+
+		entries := make(map[string]string)
+		var names []string
+		switch format {
+		case "tar":
+			r := tar.NewReader(bytes.NewReader(data))
+			for {
+				hdr, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to read tar archive: %v", err)
+				}
+				content, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("failed to read tar entry: %v", err)
+				}
+				names = append(names, hdr.Name)
+				entries[hdr.Name] = string(content)
+			}
+		case "zip":
+			r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("failed to read zip archive: %v", err)
+			}
+			for _, f := range r.File {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("failed to open zip entry: %v", err)
+				}
+				content, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("failed to read zip entry: %v", err)
+				}
+				names = append(names, f.Name)
+				entries[f.Name] = string(content)
+			}
+		}
+
+		// Entries are in target order, named by output path
+		if strings.Join(names, " ") != "web/models.ts api/models.py" {
+			t.Errorf("unexpected %s entries: %v", format, names)
+		}
+		if !strings.Contains(entries["web/models.ts"], "export interface User") {
+			t.Errorf("unexpected %s entry web/models.ts:\n%s", format, entries["web/models.ts"])
+		}
+		if !strings.Contains(entries["api/models.py"], "class User(BaseModel)") {
+			t.Errorf("unexpected %s entry api/models.py:\n%s", format, entries["api/models.py"])
+		}
+	}
+
+	// Nothing is written to disk
+	if _, err := os.Stat(filepath.Join(dir, "web")); !os.IsNotExist(err) {
+		t.Errorf("expected no output directory, got %v", err)
+	}
+
+	// Outputs outside the working directory cannot be archived
+	_, stderr, err := runGogen(t, bin, dir, "generate", "--archive", "tar", "-i", "models.go", "-t", "typescript", "-o", "../models.ts")
+	if err == nil || !strings.Contains(stderr, "outside the working directory") {
+		t.Errorf("expected ../models.ts to be rejected, got %v\n%s", err, stderr)
+	}
+
+	// Two targets cannot write the same entry
+	duplicate := files[config.FileName] + `  - name: again
+    input: models.go
+    template: typescript
+    output: web/./models.ts
+`
+	if err := os.WriteFile(filepath.Join(dir, config.FileName), []byte(duplicate), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	stdout, stderr, err := runGogen(t, bin, dir, "generate", "--archive", "zip")
+	if err == nil || stdout != "" || !strings.Contains(stderr, "web/models.ts") {
+		t.Errorf("expected duplicate entries to be rejected without output, got %v\n%s", err, stderr)
+	}
+}
+
+// TestE2E_StrictWithCache tests that --strict and JSON diagnostics do not
+// depend on whether outputs are cached.
+func TestE2E_StrictWithCache(t *testing.T) {
+	bin := buildGogen(t)
+	dir := t.TempDir()
+	src := `package models
+
+type Job struct {
+	ID   string        ` + "`json:\"id\"`" + `
+	Done chan struct{} ` + "`json:\"done\"`" + `
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "jobs.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	args := []string{"generate", "--cache-dir", t.TempDir(), "-i", "jobs.go", "-t", "typescript", "-o", "jobs.ts"}
+
+	// Without --strict, the warning does not fail, and the output is cached
+	if _, stderr, err := runGogen(t, bin, dir, args...); err != nil || !strings.Contains(stderr, "[unsupported-type]") {
+		t.Fatalf("expected a warning and success, got %v\n%s", err, stderr)
+	}
+	for i := 0; i < 2; i++ {
+		_, stderr, err := runGogen(t, bin, dir, append(args, "--strict")...)
+		if err == nil || !strings.Contains(stderr, "[unsupported-type]") {
+			t.Errorf("run %d: expected --strict to fail on the warning, got %v\n%s", i+1, err, stderr)
+		}
+		_, stderr, err = runGogen(t, bin, dir, append(args, "--format", "json")...)
+		if err != nil || !strings.Contains(stderr, `"code": "unsupported-type"`) {
+			t.Errorf("run %d: expected the warning in JSON diagnostics, got %v\n%s", i+1, err, stderr)
+		}
+	}
+}
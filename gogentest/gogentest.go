@@ -0,0 +1,49 @@
+// Package gogentest runs golden-file tests for gogen templates.
+//
+// Test cases are directories holding an input file, an optional config
+// and the expected output:
+//
+//	testdata/
+//	    users/
+//	        input.go
+//	        config.yaml     (optional)
+//	        expected.ts
+//
+// From a Go test, with a flag of the test package to accept new output:
+//
+//	var update = flag.Bool("update", false, "rewrite golden files")
+//
+//	func TestTemplate(t *testing.T) {
+//		gogentest.Run(t, "testdata", "templates/api.ts.tmpl", *update)
+//	}
+//
+// The gogen test command runs the same cases without Go code, through the
+// golden package.
+package gogentest
+
+import (
+	"testing"
+
+	"gogen/gogentest/golden"
+)
+
+// Run runs the test cases in dir against a template, each as a subtest.
+// With update, expected files are rewritten instead of compared.
+func Run(t *testing.T, dir, template string, update bool) {
+	t.Helper()
+	cases, err := golden.Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("no test cases in %s", dir)
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Check(template, update); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package golden
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 2
+
+// Diff returns a line diff from want to got: removed lines are prefixed
+// with "-", added lines with "+", and unchanged lines around them with a
+// space. Line numbers of want head each hunk.
+func Diff(want, got []byte) string {
+	a := strings.SplitAfter(string(want), "\n")
+	b := strings.SplitAfter(string(got), "\n")
+
+	// Longest common subsequence of lines, by dynamic programming from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte // ' ', '-' or '+'
+		text string
+		num  int // Line number in want
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i], i + 1})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i], i + 1})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j], i + 1})
+			j++
+		}
+	}
+
+	// Keep changes and their context
+	show := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(lines)-1, k+diffContext); c++ {
+			show[c] = true
+		}
+	}
+
+	var out strings.Builder
+	for k, l := range lines {
+		if !show[k] {
+			continue
+		}
+		if k == 0 || !show[k-1] {
+			fmt.Fprintf(&out, "@@ line %d @@\n", l.num)
+		}
+		text := strings.TrimSuffix(l.text, "\n")
+		if !strings.HasSuffix(l.text, "\n") && l.text != "" {
+			text += " (no newline at end)"
+		}
+		if l.text == "" {
+			continue
+		}
+		fmt.Fprintf(&out, "%c%s\n", l.op, text)
+	}
+	return out.String()
+}
@@ -0,0 +1,185 @@
+// Package golden compares the output of gogen templates with expected
+// files, for the gogentest package and the gogen test command.
+//
+// Test cases are directories holding an input file, an optional config
+// and the expected output:
+//
+//	testdata/
+//	    users/
+//	        input.go
+//	        config.yaml     (optional)
+//	        expected.ts
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/generator"
+	"gogen/internal/parser"
+	"gogen/templates"
+)
+
+// Files of a test case directory.
+const (
+	InputFile    = "input.go"
+	ConfigFile   = "config.yaml"
+	ExpectedName = "expected" // Expected files are named "expected.<ext>"
+)
+
+// Case is a test case directory.
+type Case struct {
+	Name string // Directory name
+	Dir  string // Directory path
+}
+
+// Discover returns the test cases in dir: its subdirectories holding an
+// input.go, sorted by name.
+func Discover(dir string) ([]Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading test cases: %w", err)
+	}
+	var cases []Case
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(caseDir, InputFile)); err == nil {
+			cases = append(cases, Case{Name: e.Name(), Dir: caseDir})
+		}
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// Expected returns the path of the expected file of the case, or "" if
+// there is none yet.
+func (c Case) Expected() (string, error) {
+	matches, err := filepath.Glob(filepath.Join(c.Dir, ExpectedName+".*"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", nil
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%s: several expected files: %s", c.Name, strings.Join(matches, ", "))
+}
+
+// Generate renders the template for the case. The input is parsed as
+// "input.go", so that positions in the output do not depend on where the
+// test data lives. Output is formatted for the extension of the expected
+// file.
+func (c Case) Generate(template string) ([]byte, error) {
+	cfg := config.New()
+	configPath := filepath.Join(c.Dir, ConfigFile)
+	if _, err := os.Stat(configPath); err == nil {
+		if err := cfg.LoadFile(configPath); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(c.Dir, InputFile))
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading input: %w", c.Name, err)
+	}
+	file, err := parser.New(parser.WithTagKey(cfg.Options.TagKey)).ParseSource(InputFile, src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name, err)
+	}
+
+	expected, err := c.expectedPath(template)
+	if err != nil {
+		return nil, err
+	}
+	gen := generator.New(cfg)
+	gen.SetOutput(expected)
+	if err := gen.LoadTemplate(template); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(file, &buf); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Check renders the template for the case and compares the output with
+// the expected file, returning a *Mismatch if they differ. With update,
+// the expected file is rewritten instead (and created as "expected.<ext>",
+// ext being the template's output extension, if missing).
+func (c Case) Check(template string, update bool) error {
+	got, err := c.Generate(template)
+	if err != nil {
+		return err
+	}
+	expected, err := c.Expected()
+	if err != nil {
+		return err
+	}
+
+	if update {
+		if expected, err = c.expectedPath(template); err != nil {
+			return err
+		}
+		if err := os.WriteFile(expected, got, 0o644); err != nil {
+			return fmt.Errorf("%s: writing expected output: %w", c.Name, err)
+		}
+		return nil
+	}
+
+	if expected == "" {
+		return fmt.Errorf("%s: no %s.* file (run with -update to create it)", c.Name, ExpectedName)
+	}
+	want, err := os.ReadFile(expected)
+	if err != nil {
+		return fmt.Errorf("%s: reading expected output: %w", c.Name, err)
+	}
+	if !bytes.Equal(want, got) {
+		return &Mismatch{Case: c.Name, Expected: expected, Diff: Diff(want, got)}
+	}
+	return nil
+}
+
+// expectedPath returns the path of the expected file of the case, or the
+// path it is created at for the template if there is none yet.
+func (c Case) expectedPath(template string) (string, error) {
+	expected, err := c.Expected()
+	if expected != "" || err != nil {
+		return expected, err
+	}
+	return filepath.Join(c.Dir, ExpectedName+outputExt(template)), nil
+}
+
+// outputExt returns the extension of outputs of a template: that of a
+// built-in template, the one before ".tmpl" (e.g., ".ts" for
+// "api.ts.tmpl"), or ".txt".
+func outputExt(template string) string {
+	if ext := templates.OutputExt(template); ext != "" {
+		return ext
+	}
+	if ext := filepath.Ext(strings.TrimSuffix(filepath.Base(template), ".tmpl")); ext != "" {
+		return ext
+	}
+	return ".txt"
+}
+
+// Mismatch reports output that differs from the expected file.
+type Mismatch struct {
+	Case     string
+	Expected string // Path of the expected file
+	Diff     string // Line diff from expected to actual output
+}
+
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("%s: output differs from %s (run with -update to accept it):\n%s", m.Case, m.Expected, m.Diff)
+}
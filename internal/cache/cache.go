@@ -0,0 +1,90 @@
+// Package cache records what generated outputs were built from, so that
+// targets whose inputs have not changed can be skipped.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cache stores one entry per output file in a directory.
+type Cache struct {
+	dir string
+}
+
+// Open opens the cache stored in dir, creating the directory if needed.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Key hashes everything an output depends on (input files, template,
+// config, gogen version) into a cache key. Parts are length-prefixed, so
+// moving bytes from one part to the next changes the key.
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(part)))
+		h.Write(n[:])
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fresh reports whether output was generated with key and has not been
+// modified or removed since.
+func (c *Cache) Fresh(output, key string) bool {
+	entry, err := os.ReadFile(c.entryPath(output))
+	if err != nil {
+		return false
+	}
+	storedKey, outputHash, ok := strings.Cut(strings.TrimSpace(string(entry)), " ")
+	if !ok || storedKey != key {
+		return false
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		return false
+	}
+	return outputHash == Key(data)
+}
+
+// Store records that output was generated with key and has the given
+// content.
+func (c *Cache) Store(output, key string, content []byte) error {
+	entry := key + " " + Key(content) + "\n"
+	if err := os.WriteFile(c.entryPath(output), []byte(entry), 0644); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	return nil
+}
+
+// entryPath returns the file holding the cache entry of an output file.
+func (c *Cache) entryPath(output string) string {
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+	return filepath.Join(c.dir, Key([]byte(output)))
+}
+
+// WriteFile writes data to path unless the file already has exactly that
+// content, so that unchanged outputs keep their modification time. It
+// reports whether the file was written.
+func WriteFile(path string, data []byte) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -12,10 +12,27 @@ import (
 
 // Config represents the complete configuration.
 type Config struct {
-	TypeMappings map[string]string `yaml:"typeMappings" json:"typeMappings"`
-	Options      Options           `yaml:"options" json:"options"`
+	TypeMappings   map[string]string            `yaml:"typeMappings" json:"typeMappings"`
+	TargetMappings map[string]map[string]string `yaml:"targetMappings" json:"targetMappings"`
+	Options        Options                      `yaml:"options" json:"options"`
+	Targets        []Target                     `yaml:"targets" json:"targets"`
 }
 
+// Target is one generation run: types of an input file rendered with a
+// template into an output file.
+type Target struct {
+	Name     string   `yaml:"name" json:"name"`
+	Input    string   `yaml:"input" json:"input"`
+	Template string   `yaml:"template" json:"template"`
+	Output   string   `yaml:"output" json:"output"`
+	Types    []string `yaml:"types,omitempty" json:"types,omitempty"`       // Overrides options.includeTypes
+	Exclude  []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`   // Overrides options.excludeTypes
+	Variants []string `yaml:"variants,omitempty" json:"variants,omitempty"` // Overrides options.variants
+}
+
+// FileName is the name of the config file found by Find.
+const FileName = ".gogen.yaml"
+
 // Options represents generation options.
 type Options struct {
 	PerType      bool     `yaml:"perType" json:"perType"`
@@ -23,13 +40,30 @@ type Options struct {
 	TagKey       string   `yaml:"tagKey" json:"tagKey"`
 	IncludeTypes []string `yaml:"includeTypes" json:"includeTypes"`
 	ExcludeTypes []string `yaml:"excludeTypes" json:"excludeTypes"`
+	LocalTypes   bool     `yaml:"localTypes" json:"localTypes"`
+	ProtoPackage string   `yaml:"protoPackage" json:"protoPackage"`
+	ProtoLock    string   `yaml:"protoLock" json:"protoLock"`
+	GraphQLInput bool     `yaml:"graphqlInput" json:"graphqlInput"`
+
+	// Fields sets flags ("nullable", "optional", "readonly", "writeonly")
+	// on fields, keyed by "Type.Field", or by "Field" for fields of any type.
+	Fields map[string][]string `yaml:"fields" json:"fields"`
+
+	// Variants lists the input types derived from every struct: "create"
+	// (CreateXInput) and "update" (UpdateXInput).
+	Variants []string `yaml:"variants" json:"variants"`
+
+	// Formatters maps output extensions (e.g., ".ts") to external formatter
+	// commands run after the built-in formatting; "none" disables formatting.
+	Formatters map[string]string `yaml:"formatters" json:"formatters"`
 }
 
 // New creates a new Config with default values.
 func New() *Config {
 	return &Config{
-		TypeMappings: DefaultTypeMappings(),
-		Options:      DefaultOptions(),
+		TypeMappings:   DefaultTypeMappings(),
+		TargetMappings: DefaultTargetMappings(),
+		Options:        DefaultOptions(),
 	}
 }
 
@@ -61,12 +95,46 @@ func (c *Config) LoadFile(path string) error {
 		}
 	}
 
+	// Paths in the config file are relative to the file, not to the
+	// working directory
+	loaded.resolvePaths(filepath.Dir(path))
+
 	// Merge loaded config with defaults
 	c.merge(&loaded)
 
 	return nil
 }
 
+// resolvePaths makes the relative paths of a loaded config relative to dir.
+// Templates named without a directory that do not exist in dir are left
+// as-is, since they name built-in templates.
+func (c *Config) resolvePaths(dir string) {
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		t.Input = resolvePath(dir, t.Input)
+		t.Output = resolvePath(dir, t.Output)
+		if tmpl := resolvePath(dir, t.Template); strings.ContainsAny(t.Template, `/\`) || fileExists(tmpl) {
+			t.Template = tmpl
+		}
+	}
+	c.Options.ProtoLock = resolvePath(dir, c.Options.ProtoLock)
+}
+
+// resolvePath joins a relative path to dir. Empty and absolute paths, and
+// "-" (stdin), are returned unchanged.
+func resolvePath(dir, path string) string {
+	if path == "" || path == "-" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// fileExists reports whether a regular file exists at path.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
 // merge merges the loaded config into the current config.
 func (c *Config) merge(loaded *Config) {
 	// Merge type mappings (loaded values override defaults)
@@ -76,6 +144,16 @@ func (c *Config) merge(loaded *Config) {
 		}
 	}
 
+	// Merge per-target type mappings
+	for target, mappings := range loaded.TargetMappings {
+		if c.TargetMappings[target] == nil {
+			c.TargetMappings[target] = make(map[string]string)
+		}
+		for k, v := range mappings {
+			c.TargetMappings[target][k] = v
+		}
+	}
+
 	// Merge options
 	if loaded.Options.TagKey != "" {
 		c.Options.TagKey = loaded.Options.TagKey
@@ -83,12 +161,60 @@ func (c *Config) merge(loaded *Config) {
 	if loaded.Options.PerType {
 		c.Options.PerType = true
 	}
+	if loaded.Options.LocalTypes {
+		c.Options.LocalTypes = true
+	}
+	if loaded.Options.ProtoPackage != "" {
+		c.Options.ProtoPackage = loaded.Options.ProtoPackage
+	}
+	if loaded.Options.ProtoLock != "" {
+		c.Options.ProtoLock = loaded.Options.ProtoLock
+	}
+	if loaded.Options.GraphQLInput {
+		c.Options.GraphQLInput = true
+	}
+	if loaded.Options.Variants != nil {
+		c.Options.Variants = loaded.Options.Variants
+	}
+	for field, flags := range loaded.Options.Fields {
+		if c.Options.Fields == nil {
+			c.Options.Fields = make(map[string][]string)
+		}
+		c.Options.Fields[field] = flags
+	}
+	for ext, command := range loaded.Options.Formatters {
+		if c.Options.Formatters == nil {
+			c.Options.Formatters = make(map[string]string)
+		}
+		c.Options.Formatters[ext] = command
+	}
+	c.Targets = append(c.Targets, loaded.Targets...)
 	// ExportedOnly defaults to true, so we check if it was explicitly set to false
 	c.Options.ExportedOnly = loaded.Options.ExportedOnly
 	c.Options.IncludeTypes = loaded.Options.IncludeTypes
 	c.Options.ExcludeTypes = loaded.Options.ExcludeTypes
 }
 
+// Find looks for FileName in dir and its parent directories, and returns
+// its path, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("finding config file: %w", err)
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
 // MapType maps a Go type to its target type using the configured mappings.
 func (c *Config) MapType(goType string) string {
 	if mapped, ok := c.TypeMappings[goType]; ok {
@@ -97,6 +223,14 @@ func (c *Config) MapType(goType string) string {
 	return goType
 }
 
+// MapTargetType maps a Go type using the mapping table of a specific
+// target language (e.g., "python"). The second result reports whether a
+// mapping was found.
+func (c *Config) MapTargetType(target, goType string) (string, bool) {
+	mapped, ok := c.TargetMappings[target][goType]
+	return mapped, ok
+}
+
 // ShouldIncludeType checks if a type should be included based on config.
 func (c *Config) ShouldIncludeType(name string, isExported bool) bool {
 	// Check exported only filter
@@ -127,3 +261,13 @@ func (c *Config) ShouldIncludeType(name string, isExported bool) bool {
 
 	return true
 }
+
+// FieldFlags returns the flags configured for a field of a type, from
+// both its "Type.Field" and "Field" entries.
+func (c *Config) FieldFlags(typeName, fieldName string) []string {
+	flags := c.Options.Fields[fieldName]
+	if typed := c.Options.Fields[typeName+"."+fieldName]; len(typed) > 0 {
+		flags = append(append([]string(nil), flags...), typed...)
+	}
+	return flags
+}
@@ -48,6 +48,290 @@ func DefaultTypeMappings() map[string]string {
 	}
 }
 
+// DefaultTargetMappings returns default Go type mappings for the built-in
+// non-TypeScript targets, keyed by target name.
+func DefaultTargetMappings() map[string]map[string]string {
+	return map[string]map[string]string{
+		"python": {
+			"string":     "str",
+			"bool":       "bool",
+			"int":        "int",
+			"int8":       "int",
+			"int16":      "int",
+			"int32":      "int",
+			"int64":      "int",
+			"uint":       "int",
+			"uint8":      "int",
+			"uint16":     "int",
+			"uint32":     "int",
+			"uint64":     "int",
+			"float32":    "float",
+			"float64":    "float",
+			"complex64":  "complex",
+			"complex128": "complex",
+			"byte":       "int",
+			"rune":       "int",
+			"uintptr":    "int",
+
+			"[]byte":        "str",
+			"time.Time":     "datetime",
+			"time.Duration": "int",
+			"interface{}":   "Any",
+			"any":           "Any",
+			"error":         "str",
+
+			"github.com/google/uuid.UUID":    "UUID",
+			"github.com/gofrs/uuid.UUID":     "UUID",
+			"github.com/satori/go.uuid.UUID": "UUID",
+
+			"github.com/shopspring/decimal.Decimal": "Decimal",
+
+			"encoding/json.RawMessage": "Any",
+		},
+		"rust": {
+			"string":     "String",
+			"bool":       "bool",
+			"int":        "i64",
+			"int8":       "i8",
+			"int16":      "i16",
+			"int32":      "i32",
+			"int64":      "i64",
+			"uint":       "u64",
+			"uint8":      "u8",
+			"uint16":     "u16",
+			"uint32":     "u32",
+			"uint64":     "u64",
+			"float32":    "f32",
+			"float64":    "f64",
+			"complex64":  "serde_json::Value",
+			"complex128": "serde_json::Value",
+			"byte":       "u8",
+			"rune":       "i32",
+			"uintptr":    "usize",
+
+			"[]byte":        "String",
+			"time.Time":     "DateTime<Utc>",
+			"time.Duration": "i64",
+			"interface{}":   "serde_json::Value",
+			"any":           "serde_json::Value",
+			"error":         "String",
+
+			"github.com/google/uuid.UUID":    "Uuid",
+			"github.com/gofrs/uuid.UUID":     "Uuid",
+			"github.com/satori/go.uuid.UUID": "Uuid",
+
+			"github.com/shopspring/decimal.Decimal": "Decimal",
+
+			"encoding/json.RawMessage": "serde_json::Value",
+		},
+		"kotlin": {
+			"string":     "String",
+			"bool":       "Boolean",
+			"int":        "Long",
+			"int8":       "Byte",
+			"int16":      "Short",
+			"int32":      "Int",
+			"int64":      "Long",
+			"uint":       "Long",
+			"uint8":      "Int",
+			"uint16":     "Int",
+			"uint32":     "Long",
+			"uint64":     "Long",
+			"float32":    "Float",
+			"float64":    "Double",
+			"complex64":  "JsonElement",
+			"complex128": "JsonElement",
+			"byte":       "Int",
+			"rune":       "Int",
+			"uintptr":    "Long",
+
+			"[]byte":        "String",
+			"time.Time":     "Instant",
+			"time.Duration": "Long",
+			"interface{}":   "JsonElement",
+			"any":           "JsonElement",
+			"error":         "String",
+
+			"github.com/google/uuid.UUID":    "String",
+			"github.com/gofrs/uuid.UUID":     "String",
+			"github.com/satori/go.uuid.UUID": "String",
+
+			"github.com/shopspring/decimal.Decimal": "String",
+
+			"encoding/json.RawMessage": "JsonElement",
+		},
+		"swift": {
+			"string":     "String",
+			"bool":       "Bool",
+			"int":        "Int",
+			"int8":       "Int8",
+			"int16":      "Int16",
+			"int32":      "Int32",
+			"int64":      "Int64",
+			"uint":       "UInt",
+			"uint8":      "UInt8",
+			"uint16":     "UInt16",
+			"uint32":     "UInt32",
+			"uint64":     "UInt64",
+			"float32":    "Float",
+			"float64":    "Double",
+			"complex64":  "AnyCodable",
+			"complex128": "AnyCodable",
+			"byte":       "UInt8",
+			"rune":       "Int32",
+			"uintptr":    "UInt",
+
+			"[]byte":        "Data",
+			"time.Time":     "Date",
+			"time.Duration": "Int64",
+			"interface{}":   "AnyCodable",
+			"any":           "AnyCodable",
+			"error":         "String",
+
+			"github.com/google/uuid.UUID":    "UUID",
+			"github.com/gofrs/uuid.UUID":     "UUID",
+			"github.com/satori/go.uuid.UUID": "UUID",
+
+			"github.com/shopspring/decimal.Decimal": "String",
+
+			"encoding/json.RawMessage": "AnyCodable",
+		},
+		"proto": {
+			"string":  "string",
+			"bool":    "bool",
+			"int":     "int64",
+			"int8":    "int32",
+			"int16":   "int32",
+			"int32":   "int32",
+			"int64":   "int64",
+			"uint":    "uint64",
+			"uint8":   "uint32",
+			"uint16":  "uint32",
+			"uint32":  "uint32",
+			"uint64":  "uint64",
+			"float32": "float",
+			"float64": "double",
+			"byte":    "uint32",
+			"rune":    "int32",
+			"uintptr": "uint64",
+
+			"[]byte":        "bytes",
+			"[]uint8":       "bytes",
+			"time.Time":     "google.protobuf.Timestamp",
+			"time.Duration": "google.protobuf.Duration",
+			"interface{}":   "google.protobuf.Value",
+			"any":           "google.protobuf.Value",
+			"error":         "string",
+
+			"github.com/google/uuid.UUID":    "string",
+			"github.com/gofrs/uuid.UUID":     "string",
+			"github.com/satori/go.uuid.UUID": "string",
+
+			"github.com/shopspring/decimal.Decimal": "string",
+
+			"encoding/json.RawMessage": "google.protobuf.Value",
+		},
+		"graphql": {
+			"string":  "String",
+			"bool":    "Boolean",
+			"int":     "Int",
+			"int8":    "Int",
+			"int16":   "Int",
+			"int32":   "Int",
+			"int64":   "Int64",
+			"uint":    "Uint64",
+			"uint8":   "Int",
+			"uint16":  "Int",
+			"uint32":  "Int64",
+			"uint64":  "Uint64",
+			"uintptr": "Uint64",
+			"float32": "Float",
+			"float64": "Float",
+			"byte":    "Int",
+			"rune":    "Int",
+
+			"[]byte":        "String",
+			"time.Time":     "DateTime",
+			"time.Duration": "Int64",
+			"interface{}":   "JSON",
+			"any":           "JSON",
+			"error":         "String",
+
+			"github.com/google/uuid.UUID":    "UUID",
+			"github.com/gofrs/uuid.UUID":     "UUID",
+			"github.com/satori/go.uuid.UUID": "UUID",
+
+			"github.com/shopspring/decimal.Decimal": "Decimal",
+
+			"encoding/json.RawMessage": "JSON",
+		},
+		"postgres": {
+			"string":  "TEXT",
+			"bool":    "BOOLEAN",
+			"int":     "BIGINT",
+			"int8":    "SMALLINT",
+			"int16":   "SMALLINT",
+			"int32":   "INTEGER",
+			"int64":   "BIGINT",
+			"uint":    "BIGINT",
+			"uint8":   "SMALLINT",
+			"uint16":  "INTEGER",
+			"uint32":  "BIGINT",
+			"uint64":  "NUMERIC(20)",
+			"float32": "REAL",
+			"float64": "DOUBLE PRECISION",
+			"byte":    "SMALLINT",
+			"rune":    "INTEGER",
+
+			"[]byte":        "BYTEA",
+			"time.Time":     "TIMESTAMPTZ",
+			"time.Duration": "BIGINT",
+			"interface{}":   "JSONB",
+			"any":           "JSONB",
+
+			"github.com/google/uuid.UUID":    "UUID",
+			"github.com/gofrs/uuid.UUID":     "UUID",
+			"github.com/satori/go.uuid.UUID": "UUID",
+
+			"github.com/shopspring/decimal.Decimal": "NUMERIC",
+
+			"encoding/json.RawMessage": "JSONB",
+		},
+		"sqlite": {
+			"string":  "TEXT",
+			"bool":    "INTEGER",
+			"int":     "INTEGER",
+			"int8":    "INTEGER",
+			"int16":   "INTEGER",
+			"int32":   "INTEGER",
+			"int64":   "INTEGER",
+			"uint":    "INTEGER",
+			"uint8":   "INTEGER",
+			"uint16":  "INTEGER",
+			"uint32":  "INTEGER",
+			"uint64":  "INTEGER",
+			"float32": "REAL",
+			"float64": "REAL",
+			"byte":    "INTEGER",
+			"rune":    "INTEGER",
+
+			"[]byte":        "BLOB",
+			"time.Time":     "TIMESTAMP",
+			"time.Duration": "INTEGER",
+			"interface{}":   "TEXT",
+			"any":           "TEXT",
+
+			"github.com/google/uuid.UUID":    "TEXT",
+			"github.com/gofrs/uuid.UUID":     "TEXT",
+			"github.com/satori/go.uuid.UUID": "TEXT",
+
+			"github.com/shopspring/decimal.Decimal": "NUMERIC",
+
+			"encoding/json.RawMessage": "TEXT",
+		},
+	}
+}
+
 // DefaultOptions returns default generation options.
 func DefaultOptions() Options {
 	return Options{
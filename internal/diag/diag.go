@@ -0,0 +1,235 @@
+// Package diag collects diagnostics (errors and warnings with source
+// positions) reported while parsing inputs and generating outputs.
+package diag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"sort"
+	"strings"
+	"sync"
+
+	"gogen/internal/model"
+)
+
+// Severity tells whether a diagnostic fails generation.
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+// String returns "warning" or "error".
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText encodes the severity as its name in JSON output.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic codes identify the kind of problem, so that tools can filter
+// or link to documentation.
+const (
+	CodeSyntax            = "syntax"             // Go source that does not parse
+	CodeUnsupportedType   = "unsupported-type"   // Field types no target can represent (chan, func)
+	CodeUnknownType       = "unknown-type"       // Type expressions the parser does not understand
+	CodeTemplate          = "template"           // Template execution failures
+	CodeUnknownField      = "unknown-field"      // Template field accesses the model does not have
+	CodeUnknownFunc       = "unknown-function"   // Template calls to undefined functions
+	CodeArity             = "arity"              // Template calls with the wrong number of arguments
+	CodeUndefinedTemplate = "undefined-template" // Template calls to undefined templates
+	CodeUnusedDefine      = "unused-define"      // Templates defined but never called
+	CodeDirective         = "directive"          // Invalid gogen directives
+	CodeMarshaler         = "marshaler"          // Marshaling methods that may not change the serialized shape
+	CodeError             = "error"              // Other errors, without a position
+)
+
+// Diagnostic is a problem found in an input or while generating. It is an
+// error, so that error diagnostics can be returned through error chains.
+type Diagnostic struct {
+	Pos      model.Position
+	Severity Severity
+	Code     string
+	Message  string
+	Err      error // Underlying error, if any
+}
+
+// Errorf creates an error diagnostic.
+func Errorf(pos model.Position, code, format string, args ...any) *Diagnostic {
+	err := fmt.Errorf(format, args...)
+	return &Diagnostic{Pos: pos, Severity: Error, Code: code, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// Warnf creates a warning diagnostic.
+func Warnf(pos model.Position, code, format string, args ...any) *Diagnostic {
+	return &Diagnostic{Pos: pos, Severity: Warning, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the message; String adds the position and severity.
+func (d *Diagnostic) Error() string {
+	return d.Message
+}
+
+// Unwrap returns the underlying error.
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// String formats the diagnostic as "file:line:col: severity: message
+// [code]", the format editors recognize.
+func (d *Diagnostic) String() string {
+	var b strings.Builder
+	if d.Pos.File != "" {
+		b.WriteString(d.Pos.String() + ": ")
+	}
+	fmt.Fprintf(&b, "%s: %s", d.Severity, d.Message)
+	if d.Code != CodeError {
+		fmt.Fprintf(&b, " [%s]", d.Code)
+	}
+	return b.String()
+}
+
+// FromError converts an error into diagnostics. Diagnostics and Go syntax
+// errors keep their positions; errors joined with errors.Join become one
+// diagnostic each; other errors become positionless error diagnostics.
+func FromError(err error) []*Diagnostic {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var diags []*Diagnostic
+		for _, e := range joined.Unwrap() {
+			diags = append(diags, FromError(e)...)
+		}
+		return diags
+	}
+
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return []*Diagnostic{d}
+	}
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		diags := make([]*Diagnostic, len(list))
+		for i, e := range list {
+			diags[i] = &Diagnostic{
+				Pos:      model.Position{File: e.Pos.Filename, Line: e.Pos.Line, Column: e.Pos.Column},
+				Severity: Error,
+				Code:     CodeSyntax,
+				Message:  e.Msg,
+			}
+		}
+		return diags
+	}
+	return []*Diagnostic{{Severity: Error, Code: CodeError, Message: err.Error(), Err: err}}
+}
+
+// Collector accumulates diagnostics. It is safe for concurrent use, and
+// its methods do nothing on a nil Collector, so reporting is optional.
+type Collector struct {
+	mu    sync.Mutex
+	diags []*Diagnostic
+}
+
+// Add records diagnostics.
+func (c *Collector) Add(diags ...*Diagnostic) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diags = append(c.diags, diags...)
+}
+
+// Warnf records a warning.
+func (c *Collector) Warnf(pos model.Position, code, format string, args ...any) {
+	c.Add(Warnf(pos, code, format, args...))
+}
+
+// Diagnostics returns the recorded diagnostics sorted by position, so that
+// they are reported in the same order however they were collected.
+// Duplicates (e.g., from an input used by several targets) are dropped.
+func (c *Collector) Diagnostics() []*Diagnostic {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	diags := append([]*Diagnostic(nil), c.diags...)
+	c.mu.Unlock()
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.Pos.File != b.Pos.File {
+			return a.Pos.File < b.Pos.File
+		}
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line < b.Pos.Line
+		}
+		if a.Pos.Column != b.Pos.Column {
+			return a.Pos.Column < b.Pos.Column
+		}
+		// Positionless diagnostics keep the order they were added in
+		return a.Pos.File != "" && a.Message < b.Message
+	})
+
+	result := diags[:0]
+	seen := make(map[string]bool)
+	for _, d := range diags {
+		key := d.String()
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// Count returns the number of recorded diagnostics of a severity.
+func (c *Collector) Count(severity Severity) int {
+	n := 0
+	for _, d := range c.Diagnostics() {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// jsonDiagnostic is the JSON form of a diagnostic, with the position
+// flattened as editors expect.
+type jsonDiagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// MarshalJSON encodes the diagnostic with a flat position.
+func (d *Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonDiagnostic{
+		File:     d.Pos.File,
+		Line:     d.Pos.Line,
+		Column:   d.Pos.Column,
+		Severity: d.Severity,
+		Code:     d.Code,
+		Message:  d.Message,
+	})
+}
+
+// JSON encodes diagnostics as a JSON array.
+func JSON(diags []*Diagnostic) ([]byte, error) {
+	if diags == nil {
+		diags = []*Diagnostic{}
+	}
+	return json.MarshalIndent(diags, "", "  ")
+}
@@ -0,0 +1,299 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gogen/internal/model"
+	"gogen/templates"
+)
+
+// formatter post-processes generated output of one kind.
+type formatter func(src []byte, file *model.File) ([]byte, error)
+
+// formatters holds the built-in formatters, keyed by output extension.
+var formatters = map[string]formatter{
+	".go":   func(src []byte, file *model.File) ([]byte, error) { return formatGo(src, file.Imports) },
+	".ts":   formatBraces,
+	".tsx":  formatBraces,
+	".js":   formatBraces,
+	".mjs":  formatBraces,
+	".json": formatJSON,
+	".yaml": formatYAML,
+	".yml":  formatYAML,
+}
+
+// formatError reports a formatting failure at a line of generated output.
+type formatError struct {
+	Line int    // 1-based line number in the generated output
+	Text string // The offending line
+	Err  error
+}
+
+func (e *formatError) Error() string {
+	return fmt.Sprintf("line %d: %v\n\t%d | %s", e.Line, e.Err, e.Line, e.Text)
+}
+
+func (e *formatError) Unwrap() error {
+	return e.Err
+}
+
+// lineError returns a formatError for a line of src.
+func lineError(src []byte, line int, err error) error {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return err
+	}
+	return &formatError{Line: line, Text: strings.TrimRight(lines[line-1], "\r"), Err: err}
+}
+
+// goSourceError locates an error reported by go/parser or go/format.
+func goSourceError(src []byte, err error) error {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		return lineError(src, list[0].Pos.Line, errors.New(list[0].Msg))
+	}
+	return err
+}
+
+// outputExt returns the extension deciding how output is formatted: that
+// of the output file, or failing that the one a template name implies
+// (that of the built-in template, e.g., ".sql" for "sql-postgres", or of
+// "*.go.tmpl"-style double extensions), so that output written to stdout
+// or an archive is treated like output written to a file.
+func outputExt(outputPath, templateName string) string {
+	if ext := filepath.Ext(outputPath); ext != "" {
+		return strings.ToLower(ext)
+	}
+	name := strings.TrimSuffix(filepath.Base(templateName), ".tmpl")
+	if ext := templates.OutputExt(name); ext != "" {
+		return ext
+	}
+	if strings.HasPrefix(name, "go-") {
+		return ".go"
+	}
+	return strings.ToLower(filepath.Ext(name))
+}
+
+// keepsDeclaredShapes reports whether the output describes the Go types
+// themselves (Go source) or how they are stored (SQL), rather than how they
+// serialize: such outputs keep declared structs, fields and shapes.
+func (g *Generator) keepsDeclaredShapes() bool {
+	switch outputExt(g.outputPath, g.template.Name()) {
+	case ".go", ".sql":
+		return true
+	}
+	return false
+}
+
+// format runs the built-in formatter and the configured external formatter
+// for the output extension. An external formatter of "none" disables
+// formatting altogether.
+func (g *Generator) format(src []byte, file *model.File) ([]byte, error) {
+	ext := outputExt(g.outputPath, g.template.Name())
+	command, ok := g.config.Options.Formatters[ext]
+	if !ok {
+		command = g.config.Options.Formatters[strings.TrimPrefix(ext, ".")]
+	}
+	if command == "none" {
+		return src, nil
+	}
+
+	if f, ok := formatters[ext]; ok {
+		formatted, err := f(src, file)
+		if err != nil {
+			return nil, fmt.Errorf("formatting %s output: %w", ext, err)
+		}
+		src = formatted
+	}
+	if command != "" {
+		formatted, err := runFormatter(command, g.outputPath, src)
+		if err != nil {
+			return nil, err
+		}
+		src = formatted
+	}
+	return src, nil
+}
+
+// runFormatter pipes src through an external formatter command. "{file}"
+// in the command is replaced with the output path, for formatters that
+// pick their settings by file name (e.g., "prettier --stdin-filepath {file}").
+func runFormatter(command, outputPath string, src []byte) ([]byte, error) {
+	args := strings.Fields(strings.ReplaceAll(command, "{file}", outputPath))
+	if len(args) == 0 {
+		return src, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("running formatter %q: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("running formatter %q: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// normalizeWhitespace trims trailing whitespace, collapses runs of blank
+// lines and leading blank lines, and ends the output with one newline.
+// With braces set, blank lines just inside brackets are removed as well.
+func normalizeWhitespace(src []byte, braces bool) []byte {
+	lines := strings.Split(string(src), "\n")
+	var out []string
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if len(out) == 0 || out[len(out)-1] == "" {
+				continue
+			}
+			if braces && strings.ContainsAny(out[len(out)-1][len(out[len(out)-1])-1:], "{([") {
+				continue
+			}
+		} else if trimmed := strings.TrimSpace(line); braces && len(out) > 0 && out[len(out)-1] == "" && trimmed != "" && strings.ContainsAny(trimmed[:1], "})]") {
+			out = out[:len(out)-1]
+		}
+		out = append(out, line)
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// formatBraces normalizes the whitespace of brace-delimited languages (such
+// as TypeScript) and checks that their brackets are balanced.
+func formatBraces(src []byte, _ *model.File) ([]byte, error) {
+	src = normalizeWhitespace(src, true)
+	if err := checkBrackets(src); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// checkBrackets reports the first unbalanced bracket, skipping strings,
+// template literals and comments.
+func checkBrackets(src []byte) error {
+	type open struct {
+		char byte
+		line int
+	}
+	closers := map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+	var stack []open
+	line := 1
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			for i += 2; i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/'); i++ {
+				if src[i] == '\n' {
+					line++
+				}
+			}
+			i++
+		case c == '/' && regexAllowed(src[:i]):
+			// Regular expression literal, which may contain unbalanced
+			// brackets inside character classes
+			class := false
+			for i++; i < len(src) && src[i] != '\n'; i++ {
+				if src[i] == '\\' {
+					i++
+				} else if src[i] == '[' {
+					class = true
+				} else if src[i] == ']' {
+					class = false
+				} else if src[i] == '/' && !class {
+					break
+				}
+			}
+		case c == '"' || c == '\'' || c == '`':
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' {
+					i++
+				} else if src[i] == '\n' {
+					line++
+				}
+			}
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, open{c, line})
+		case closers[c] != 0:
+			if len(stack) == 0 || stack[len(stack)-1].char != closers[c] {
+				return lineError(src, line, fmt.Errorf("unexpected %q", c))
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		last := stack[len(stack)-1]
+		return lineError(src, last.line, fmt.Errorf("unclosed %q", last.char))
+	}
+	return nil
+}
+
+// regexAllowed reports whether a "/" following before starts a regular
+// expression literal rather than a division.
+func regexAllowed(before []byte) bool {
+	before = bytes.TrimRight(before, " \t")
+	if len(before) == 0 {
+		return true
+	}
+	return strings.IndexByte("(,=:[!&|?{};\n", before[len(before)-1]) >= 0
+}
+
+// formatJSON re-indents JSON output with two spaces.
+func formatJSON(src []byte, _ *model.File) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(src), "", "  "); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			trimmed := bytes.TrimSpace(src)
+			return nil, lineError(trimmed, bytes.Count(trimmed[:syntaxErr.Offset], []byte("\n"))+1, err)
+		}
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// yamlLineRe extracts the line number from yaml.v3 error messages.
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// formatYAML normalizes the whitespace of YAML output and checks that it
+// parses.
+func formatYAML(src []byte, _ *model.File) ([]byte, error) {
+	src = normalizeWhitespace(src, false)
+	var node yaml.Node
+	if err := yaml.Unmarshal(src, &node); err != nil {
+		if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, lineError(src, line, err)
+		}
+		return nil, err
+	}
+	return src, nil
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"text/template"
 	"unicode"
@@ -24,6 +25,10 @@ func templateFuncs(cfg *config.Config) template.FuncMap {
 			return mapType(cfg, t)
 		},
 
+		"targetType": func(target string, t model.TypeRef) string {
+			return mapTargetType(cfg, target, t)
+		},
+
 		// String manipulation
 		"camelCase":  camelCase,
 		"pascalCase": pascalCase,
@@ -41,6 +46,13 @@ func templateFuncs(cfg *config.Config) template.FuncMap {
 		"tagOrName": func(f model.Field) string { return tagOrName(f, cfg.Options.TagKey) },
 		"jsonName":  jsonName,
 		"hasTag":    hasTag,
+		"tagName":   tagName,
+		"tagOptions": func(f model.Field, key string) []string {
+			return f.Tag.Options[key].Options
+		},
+		"hasTagOption": func(f model.Field, key, opt string) bool {
+			return f.Tag.HasOption(key, opt)
+		},
 
 		// Type helpers
 		"isStruct":    func(t model.TypeRef) bool { return t.Kind == model.KindStruct },
@@ -50,10 +62,21 @@ func templateFuncs(cfg *config.Config) template.FuncMap {
 		"isPointer":   func(t model.TypeRef) bool { return t.Kind == model.KindPointer },
 		"isBasic":     func(t model.TypeRef) bool { return t.Kind == model.KindBasic },
 		"isInterface": func(t model.TypeRef) bool { return t.Kind == model.KindInterface },
+		"isChan":      func(t model.TypeRef) bool { return t.Kind == model.KindChan },
+		"isFunc":      func(t model.TypeRef) bool { return t.Kind == model.KindFunc },
+		"isTuple":     isTuple,
+		"isType":      isType,
+		"isUUID":      isUUID,
 		"isOptional":  isOptional,
+		"nonNull":     nonNull,
+		"mapFieldType": func(f model.Field) string {
+			return mapFieldType(cfg, f)
+		},
+		"tsProperty": func(f model.Field) string { return tsProperty(cfg, f) },
 		"elemType": func(t model.TypeRef) *model.TypeRef {
 			return t.Elem
 		},
+		"unflatten": unflatten,
 		"keyType": func(t model.TypeRef) *model.TypeRef {
 			return t.Key
 		},
@@ -70,11 +93,78 @@ func templateFuncs(cfg *config.Config) template.FuncMap {
 		"ternary": ternary,
 
 		// Comment formatting
-		"comment":    formatComment,
-		"docComment": formatDocComment,
+		"comment":       formatComment,
+		"docComment":    formatDocComment,
+		"source":        sourceLocation,
+		"sourceComment": func(pos model.Position) string { return "// source: " + sourceLocation(pos) },
 
 		// Misc
 		"notLast": func(i, length int) bool { return i < length-1 },
+		"seq":     seq,
+
+		// Enum helpers (typed constants)
+		"enumKind":  enumKind,
+		"constName": constName,
+
+		// Python (Pydantic) helpers
+		"pyName":       pyName,
+		"pyType":       func(f model.Field) string { return pyType(cfg, f) },
+		"pyField":      func(f model.Field) string { return pyField(cfg, f) },
+		"pyEnumBase":   pyEnumBase,
+		"pyEnumMember": pyEnumMember,
+		"pyImports":    func(types []model.Type) []string { return pyImports(cfg, types) },
+
+		// Rust (serde) helpers
+		"rustName":     rustName,
+		"rustType":     func(t model.Type, f model.Field) string { return rustType(cfg, t, f) },
+		"rustField":    func(t model.Type, f model.Field) string { return rustField(cfg, t, f) },
+		"rustEnumRepr": func(t model.Type) string { return rustEnumRepr(cfg, t) },
+		"rustImports":  func(types []model.Type) []string { return rustImportLines(cfg, types) },
+
+		// Kotlin (kotlinx.serialization) helpers
+		"ktName":      ktName,
+		"ktType":      func(f model.Field) string { return ktType(cfg, f) },
+		"ktField":     func(f model.Field) string { return ktField(cfg, f) },
+		"ktEnumEntry": ktEnumEntry,
+		"ktEnumPrimitive": func(t model.Type) string {
+			return ktEnumPrimitive(cfg, t)
+		},
+		"ktEnumLiteral": ktEnumLiteral,
+		"ktImports":     func(types []model.Type) []string { return ktImportLines(cfg, types) },
+
+		// Swift (Codable) helpers
+		"swiftName":        swiftName,
+		"swiftType":        func(f model.Field) string { return swiftType(cfg, f) },
+		"swiftCodingKey":   func(f model.Field) string { return swiftCodingKey(cfg, f) },
+		"swiftEnumCase":    swiftEnumCase,
+		"swiftIsRecursive": swiftIsRecursive,
+		"swiftUsesAnyCodable": func(types []model.Type) bool {
+			return swiftUsesAnyCodable(cfg, types)
+		},
+
+		// Protocol Buffers helpers (field numbering lives in Generator.stateFuncs)
+		"protoImports": func(types []model.Type) []string { return protoImportLines(cfg, types) },
+
+		// GraphQL SDL helpers
+		"gqlType": func(f model.Field, all []model.Type) string { return gqlType(cfg, f, all, false) },
+		"gqlInputType": func(f model.Field, all []model.Type) string {
+			return gqlType(cfg, f, all, true)
+		},
+		"gqlScalars": func(types, all []model.Type) []string {
+			return gqlScalars(cfg, types, all)
+		},
+		"gqlEnumValue":   gqlEnumValue,
+		"gqlDescription": gqlDescription,
+
+		// SQL DDL helpers
+		"sqlTables": func(dialect string, types []model.Type) ([]sqlTable, error) {
+			return sqlTables(cfg, dialect, types)
+		},
+
+		// Go helpers
+		"goReceiver": goReceiver,
+		"goValidate": func(t model.Type, all []model.Type) string { return goValidate(cfg, t, all) },
+		"goClone":    goClone,
 
 		// Valibot form helpers
 		"valibotFormField": valibotFormField,
@@ -85,7 +175,16 @@ func templateFuncs(cfg *config.Config) template.FuncMap {
 
 // mapType maps a Go type to the target language type.
 func mapType(cfg *config.Config, t model.TypeRef) string {
-	// Check for exact raw match first
+	// Check for full import path match first (github.com/google/uuid.UUID),
+	// so aliased imports resolve to the right mapping
+	if t.PkgPath != "" {
+		qualified := t.QualifiedName()
+		if mapped := cfg.MapType(qualified); mapped != qualified {
+			return mapped
+		}
+	}
+
+	// Check for exact raw match
 	if mapped := cfg.MapType(t.Raw); mapped != t.Raw {
 		return mapped
 	}
@@ -105,10 +204,36 @@ func mapType(cfg *config.Config, t model.TypeRef) string {
 
 	// Handle composite types
 	switch t.Kind {
-	case model.KindSlice, model.KindArray:
+	case model.KindArray:
+		if t.Elem != nil && isTuple(t) {
+			// Short fixed-length arrays become tuples
+			elem := mapType(cfg, *t.Elem)
+			elems := make([]string, t.Len)
+			for i := range elems {
+				elems[i] = elem
+			}
+			return "[" + strings.Join(elems, ", ") + "]"
+		}
 		if t.Elem != nil {
 			return mapType(cfg, *t.Elem) + "[]"
 		}
+	case model.KindSlice:
+		if t.Elem != nil {
+			return mapType(cfg, *t.Elem) + "[]"
+		}
+	case model.KindStruct:
+		// Anonymous structs become inline object types
+		if len(t.Fields) == 0 {
+			return "{}"
+		}
+		var props []string
+		for _, f := range t.Fields {
+			if !f.IsExported {
+				continue
+			}
+			props = append(props, tsProperty(cfg, f))
+		}
+		return "{ " + strings.Join(props, "; ") + " }"
 	case model.KindMap:
 		if t.Key != nil && t.Value != nil {
 			return "Record<" + mapType(cfg, *t.Key) + ", " + mapType(cfg, *t.Value) + ">"
@@ -117,7 +242,7 @@ func mapType(cfg *config.Config, t model.TypeRef) string {
 		if t.Elem != nil {
 			return mapType(cfg, *t.Elem) + " | null"
 		}
-	case model.KindInterface:
+	case model.KindInterface, model.KindChan, model.KindFunc:
 		return "unknown"
 	}
 
@@ -127,15 +252,17 @@ func mapType(cfg *config.Config, t model.TypeRef) string {
 
 // tagOrName returns the tag value for key, or the field name.
 func tagOrName(field model.Field, key string) string {
-	if val, ok := field.Tag.Values[key]; ok {
-		parts := strings.Split(val, ",")
-		if parts[0] != "" && parts[0] != "-" {
-			return parts[0]
-		}
+	if name := tagName(field, key); name != "" && name != "-" {
+		return name
 	}
 	return field.Name
 }
 
+// tagName returns the name part of a tag value (before the first comma).
+func tagName(field model.Field, key string) string {
+	return field.Tag.Options[key].Name
+}
+
 // jsonName returns the JSON field name.
 func jsonName(field model.Field) string {
 	return tagOrName(field, "json")
@@ -152,15 +279,103 @@ func hasTag(field model.Field, key string) bool {
 	return ok
 }
 
-// isOptional checks if a field is optional (pointer or has omitempty).
+// uuidTypes are the UUID types of common libraries. "uuid.UUID" matches
+// references whose import path is unknown.
+var uuidTypes = []string{
+	"github.com/google/uuid.UUID",
+	"github.com/gofrs/uuid.UUID",
+	"github.com/satori/go.uuid.UUID",
+	"uuid.UUID",
+}
+
+// isType reports whether t is one of the named types, given by their
+// import path (e.g., "github.com/google/uuid.UUID"), so that the local
+// name of the package does not matter.
+func isType(t model.TypeRef, names ...string) bool {
+	qualified := t.QualifiedName()
+	for _, name := range names {
+		if qualified == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isUUID reports whether t is the UUID type of a common library.
+func isUUID(t model.TypeRef) bool {
+	return isType(t, uuidTypes...)
+}
+
+// maxTupleLen is the longest fixed-length array rendered as a tuple;
+// longer arrays (e.g., [32]byte) are rendered as arrays.
+const maxTupleLen = 16
+
+// isTuple reports whether an array type is rendered as a tuple.
+func isTuple(t model.TypeRef) bool {
+	return t.Kind == model.KindArray && t.Len > 0 && t.Len <= maxTupleLen
+}
+
+// isOptional reports whether a field may be null or absent, for targets
+// that do not tell the two apart.
 func isOptional(field model.Field) bool {
-	if field.Type.Kind == model.KindPointer {
-		return true
+	return field.Nullable || field.Optional
+}
+
+// nonNull returns the type of a field without its pointer, for templates
+// that render nullability from the field flags.
+func nonNull(t model.TypeRef) model.TypeRef {
+	if t.Kind == model.KindPointer && t.Elem != nil {
+		return *t.Elem
 	}
-	if jsonTag, ok := field.Tag.Values["json"]; ok {
-		return strings.Contains(jsonTag, "omitempty")
+	return t
+}
+
+// mapFieldType maps the type of a field to TypeScript, adding "| null"
+// for nullable fields.
+func mapFieldType(cfg *config.Config, f model.Field) string {
+	typ := mapType(cfg, nonNull(f.Type))
+	if f.Nullable {
+		typ += " | null"
 	}
-	return false
+	return typ
+}
+
+// tsProperty returns the TypeScript property declaration of a field:
+// "readonly name?: T | null" with the parts its flags call for.
+func tsProperty(cfg *config.Config, f model.Field) string {
+	prop := tagOrName(f, cfg.Options.TagKey)
+	if f.Readonly {
+		prop = "readonly " + prop
+	}
+	if f.Optional {
+		prop += "?"
+	}
+	return prop + ": " + mapFieldType(cfg, f)
+}
+
+// unflatten collapses fields promoted from an embedded struct back into a
+// single embedded field, for targets that model embedding themselves
+// (e.g., #[serde(flatten)] in Rust).
+func unflatten(fields []model.Field) []model.Field {
+	var result []model.Field
+	for _, f := range fields {
+		if f.Via == "" {
+			result = append(result, f)
+			continue
+		}
+		if n := len(result); n > 0 && result[n-1].IsEmbedded && result[n-1].Name == f.Via {
+			continue
+		}
+		result = append(result, model.Field{
+			Name:       f.Via,
+			Type:       model.TypeRef{Kind: model.KindBasic, Name: f.Via, Raw: f.Via},
+			Tag:        model.StructTag{Values: map[string]string{}, Options: map[string]model.TagValue{}},
+			IsExported: unicode.IsUpper([]rune(f.Via)[0]),
+			IsEmbedded: true,
+			Pos:        f.Pos,
+		})
+	}
+	return result
 }
 
 // camelCase converts to camelCase.
@@ -274,6 +489,14 @@ func formatDocComment(comment string) string {
 	return strings.Join(result, "\n")
 }
 
+// sourceLocation returns a short "file.go:line" reference for a position.
+func sourceLocation(pos model.Position) string {
+	if !pos.IsValid() {
+		return filepath.Base(pos.File)
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(pos.File), pos.Line)
+}
+
 // containsStr checks if a slice contains a string.
 func containsStr(slice []string, s string) bool {
 	for _, item := range slice {
@@ -300,6 +523,15 @@ func ternary(condition bool, a, b string) string {
 	return b
 }
 
+// seq returns the integers 0..n-1, for ranging over fixed-length arrays.
+func seq(n int) []int {
+	result := make([]int, n)
+	for i := range result {
+		result[i] = i
+	}
+	return result
+}
+
 // parseValidateTag parses a validate struct tag and returns a list of rules.
 // Input: "required,min=1,max=45"
 // Output: []ValidateRule{{Name: "required"}, {Name: "min", Value: "1"}, {Name: "max", Value: "45"}}
@@ -381,19 +613,27 @@ func valibotFormField(field model.Field) string {
 		}
 	case model.KindNamed:
 		// Handle special named types
-		if field.Type.Raw == "time.Time" {
+		if isType(field.Type, "time.Time") {
 			baseType = "v.string()"
 			defaultVal = "''"
-		} else if field.Type.Raw == "uuid.UUID" {
+		} else if isUUID(field.Type) {
 			baseType = "v.string()"
 			defaultVal = "''"
 		} else {
 			// Reference to another schema
 			return fmt.Sprintf("%sSchema", field.Type.Name)
 		}
-	case model.KindSlice, model.KindArray:
+	case model.KindArray:
+		if isTuple(field.Type) {
+			return valibotElemType(&field.Type)
+		}
+		elemType := valibotElemType(field.Type.Elem)
+		return fmt.Sprintf("v.optional(v.array(%s), [])", elemType)
+	case model.KindSlice:
 		elemType := valibotElemType(field.Type.Elem)
 		return fmt.Sprintf("v.optional(v.array(%s), [])", elemType)
+	case model.KindStruct:
+		return valibotElemType(&field.Type)
 	case model.KindMap:
 		keyType := valibotElemType(field.Type.Key)
 		valueType := valibotElemType(field.Type.Value)
@@ -446,6 +686,18 @@ func valibotFormField(field model.Field) string {
 	return fmt.Sprintf("v.pipe(%s)", strings.Join(parts, ", "))
 }
 
+// valibotWrapper returns the Valibot schema wrapping the type of a
+// nullable or optional field.
+func valibotWrapper(f model.Field) string {
+	switch {
+	case f.Nullable && f.Optional:
+		return "v.nullish"
+	case f.Nullable:
+		return "v.nullable"
+	}
+	return "v.optional"
+}
+
 // valibotElemType returns the Valibot type for a TypeRef element.
 func valibotElemType(t *model.TypeRef) string {
 	if t == nil {
@@ -467,14 +719,37 @@ func valibotElemType(t *model.TypeRef) string {
 			return "v.unknown()"
 		}
 	case model.KindNamed:
-		if t.Raw == "time.Time" {
+		if isType(*t, "time.Time") {
 			return "v.pipe(v.string(), v.isoDateTime())"
-		} else if t.Raw == "uuid.UUID" {
+		} else if isUUID(*t) {
 			return "v.pipe(v.string(), v.uuid())"
 		}
 		return fmt.Sprintf("%sSchema", t.Name)
-	case model.KindSlice, model.KindArray:
+	case model.KindArray:
+		if isTuple(*t) {
+			elems := make([]string, t.Len)
+			for i := range elems {
+				elems[i] = valibotElemType(t.Elem)
+			}
+			return fmt.Sprintf("v.tuple([%s])", strings.Join(elems, ", "))
+		}
 		return fmt.Sprintf("v.array(%s)", valibotElemType(t.Elem))
+	case model.KindSlice:
+		return fmt.Sprintf("v.array(%s)", valibotElemType(t.Elem))
+	case model.KindStruct:
+		var entries []string
+		for _, f := range t.Fields {
+			if !f.IsExported {
+				continue
+			}
+			entry := valibotElemType(&f.Type)
+			if f.Nullable || f.Optional {
+				base := nonNull(f.Type)
+				entry = fmt.Sprintf("%s(%s)", valibotWrapper(f), valibotElemType(&base))
+			}
+			entries = append(entries, fmt.Sprintf("%s: %s", jsonName(f), entry))
+		}
+		return fmt.Sprintf("v.object({ %s })", strings.Join(entries, ", "))
 	case model.KindMap:
 		return fmt.Sprintf("v.record(%s, %s)", valibotElemType(t.Key), valibotElemType(t.Value))
 	case model.KindPointer:
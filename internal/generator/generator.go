@@ -2,19 +2,29 @@
 package generator
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"text/template"
 
 	"gogen/internal/config"
+	"gogen/internal/diag"
 	"gogen/internal/model"
+	"gogen/templates"
 )
 
 // Generator executes templates against parsed types.
 type Generator struct {
-	config   *config.Config
-	template *template.Template
+	config     *config.Config
+	template   *template.Template
+	protoLock  *protoLock      // Field numbers for proto output, loaded per Generate call
+	protoLocks *ProtoLocks     // Lock files shared with other generators (may be nil)
+	outputPath string          // Output file, which decides how output is formatted
+	regions    *regions        // Protected regions of the existing output, loaded per Generate call
+	command    string          // Command line that reproduces the output, shown in headers
+	diags      *diag.Collector // Receives warnings about types a target cannot represent (may be nil)
 }
 
 // New creates a new Generator.
@@ -24,10 +34,18 @@ func New(cfg *config.Config) *Generator {
 	}
 }
 
-// LoadTemplate loads a template from file.
+// LoadTemplate loads a template from file. If no file exists at path and
+// path names a built-in template (e.g., "pydantic"), the built-in is used.
 func (g *Generator) LoadTemplate(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if name, ok := templates.Lookup(path); ok {
+			return g.loadBuiltinTemplate(name)
+		}
+	}
+
 	tmpl, err := template.New(filepath.Base(path)).
 		Funcs(templateFuncs(g.config)).
+		Funcs(g.stateFuncs()).
 		ParseFiles(path)
 	if err != nil {
 		return fmt.Errorf("loading template: %w", err)
@@ -36,6 +54,44 @@ func (g *Generator) LoadTemplate(path string) error {
 	return nil
 }
 
+// loadBuiltinTemplate loads one of the templates embedded in gogen.
+func (g *Generator) loadBuiltinTemplate(name string) error {
+	tmpl, err := template.New(name).
+		Funcs(templateFuncs(g.config)).
+		Funcs(g.stateFuncs()).
+		ParseFS(templates.FS, name)
+	if err != nil {
+		return fmt.Errorf("loading built-in template %s: %w", name, err)
+	}
+	g.template = tmpl
+	return nil
+}
+
+// SetOutput tells the generator where its output is written, so output
+// can be formatted for its language (e.g., gofmt for ".go" files).
+func (g *Generator) SetOutput(path string) {
+	g.outputPath = path
+}
+
+// SetCommand records the command line that generates the output, which
+// templates show in their header so that it can be reproduced.
+func (g *Generator) SetCommand(command string) {
+	g.command = command
+}
+
+// SetDiagnostics makes the generator report warnings about types the
+// target cannot represent exactly (e.g., repeated maps in proto) to c.
+func (g *Generator) SetDiagnostics(c *diag.Collector) {
+	g.diags = c
+}
+
+// SetProtoLocks makes the generator take its proto lock from locks, shared
+// with the other generators of a run, instead of reading the file on each
+// Generate call. The shared locks are saved with locks.Save.
+func (g *Generator) SetProtoLocks(locks *ProtoLocks) {
+	g.protoLocks = locks
+}
+
 // TemplateData represents data passed to templates.
 type TemplateData struct {
 	File         *model.File       // The parsed file
@@ -43,10 +99,74 @@ type TemplateData struct {
 	Type         *model.Type       // Current type (for per-type mode)
 	Config       *config.Config    // Configuration
 	TypeMappings map[string]string // Type mappings for convenience
+	Command      string            // Command line that generates the output (may be empty)
+}
+
+// stateFuncs returns the template functions that depend on state kept by
+// the generator across a Generate call.
+func (g *Generator) stateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"protoFields": func(t model.Type, all []model.Type) []protoField {
+			return protoFieldsOf(g.config, g.protoLock, g.diags, t, all)
+		},
+		"protoEnumValues": func(t model.Type) []protoEnumValue {
+			return protoEnumValuesOf(g.protoLock, t)
+		},
+		"protoReserved": func(t model.Type) protoReserved {
+			return protoReservedOf(g.config, g.protoLock, t)
+		},
+		"region": func(name string, prefix ...string) (string, error) {
+			return g.regions.region(outputExt(g.outputPath, g.template.Name()), name, prefix...)
+		},
+	}
 }
 
 // Generate generates output for all types.
 func (g *Generator) Generate(file *model.File, w io.Writer) error {
+	locks := g.protoLocks
+	if locks == nil {
+		locks = NewProtoLocks()
+	}
+	lock, err := locks.load(g.config.Options.ProtoLock)
+	if err != nil {
+		return err
+	}
+	g.protoLock = lock
+
+	g.regions, err = loadRegions(g.outputPath)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := g.generate(file, &buf); err != nil {
+		return err
+	}
+	if err := g.regions.check(); err != nil {
+		return err
+	}
+	src, err := g.format(buf.Bytes(), file)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(src); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// SaveProtoLock writes the proto field numbers assigned by the last
+// Generate call to the options.protoLock file. Generate never writes the
+// file itself, so that outputs can be checked without modifying the tree.
+func (g *Generator) SaveProtoLock() error {
+	if g.protoLock == nil {
+		return nil
+	}
+	return g.protoLock.save()
+}
+
+// generate executes the template for the filtered types.
+func (g *Generator) generate(file *model.File, w io.Writer) error {
 	types := g.filterTypes(file.Types)
 
 	// Build type map for embedded field flattening
@@ -58,18 +178,33 @@ func (g *Generator) Generate(file *model.File, w io.Writer) error {
 	// Flatten embedded fields
 	types = g.flattenEmbedded(types, typeMap)
 
+	types, err := g.applyFieldFlags(types)
+	if err != nil {
+		return err
+	}
+	types, err = g.applyMarshalers(types)
+	if err != nil {
+		return err
+	}
+	types, err = g.expandVariants(types)
+	if err != nil {
+		return err
+	}
+
 	if g.config.Options.PerType {
 		// Execute template once per type
 		for i := range types {
+			g.regions.reset()
 			data := &TemplateData{
 				File:         file,
 				Types:        types,
 				Type:         &types[i],
 				Config:       g.config,
 				TypeMappings: g.config.TypeMappings,
+				Command:      g.command,
 			}
 			if err := g.template.Execute(w, data); err != nil {
-				return fmt.Errorf("executing template for %s: %w", types[i].Name, err)
+				return diag.Errorf(types[i].Pos, diag.CodeTemplate, "executing template for %s (%s): %w", types[i].Name, sourceLocation(types[i].Pos), err)
 			}
 		}
 	} else {
@@ -79,8 +214,12 @@ func (g *Generator) Generate(file *model.File, w io.Writer) error {
 			Types:        types,
 			Config:       g.config,
 			TypeMappings: g.config.TypeMappings,
+			Command:      g.command,
 		}
 		if err := g.template.Execute(w, data); err != nil {
+			if t := g.failingType(file, types); t != nil {
+				return diag.Errorf(t.Pos, diag.CodeTemplate, "executing template for %s (%s): %w", t.Name, sourceLocation(t.Pos), err)
+			}
 			return fmt.Errorf("executing template: %w", err)
 		}
 	}
@@ -88,6 +227,34 @@ func (g *Generator) Generate(file *model.File, w io.Writer) error {
 	return nil
 }
 
+// failingType finds the type whose declaration makes the template fail, by
+// re-executing the template with each type on its own. It returns nil when
+// the failure does not depend on a single type, e.g., when the template
+// also fails without any type.
+func (g *Generator) failingType(file *model.File, types []model.Type) *model.Type {
+	execute := func(types []model.Type) error {
+		g.regions.reset()
+		data := &TemplateData{
+			File:         file,
+			Types:        types,
+			Config:       g.config,
+			TypeMappings: g.config.TypeMappings,
+			Command:      g.command,
+		}
+		return g.template.Execute(io.Discard, data)
+	}
+
+	if execute(nil) != nil {
+		return nil
+	}
+	for i := range types {
+		if execute(types[i:i+1]) != nil {
+			return &types[i]
+		}
+	}
+	return nil
+}
+
 // filterTypes filters types based on configuration.
 func (g *Generator) filterTypes(types []model.Type) []model.Type {
 	var result []model.Type
@@ -119,6 +286,37 @@ func (g *Generator) flattenEmbedded(types []model.Type, typeMap map[string]model
 	return result
 }
 
+// applyFieldFlags sets the field flags configured in options.fields. The
+// fields of the types are copied, so that the parsed file is not modified.
+func (g *Generator) applyFieldFlags(types []model.Type) ([]model.Type, error) {
+	if len(g.config.Options.Fields) == 0 {
+		return types, nil
+	}
+	for i, t := range types {
+		fields := make([]model.Field, len(t.Fields))
+		copy(fields, t.Fields)
+		for j := range fields {
+			f := &fields[j]
+			for _, flag := range g.config.FieldFlags(t.Name, f.Name) {
+				switch flag {
+				case "nullable":
+					f.Nullable = true
+				case "optional":
+					f.Optional = true
+				case "readonly":
+					f.Readonly = true
+				case "writeonly":
+					f.WriteOnly = true
+				default:
+					return nil, fmt.Errorf("options.fields: unknown flag %q for %s.%s (want nullable, optional, readonly or writeonly)", flag, t.Name, f.Name)
+				}
+			}
+		}
+		types[i].Fields = fields
+	}
+	return types, nil
+}
+
 // flattenFields recursively flattens embedded fields.
 func (g *Generator) flattenFields(fields []model.Field, typeMap map[string]model.Type, seen map[string]bool) []model.Field {
 	var result []model.Field
@@ -150,8 +348,12 @@ func (g *Generator) flattenFields(fields []model.Field, typeMap map[string]model
 			continue
 		}
 
-		// Recursively flatten the embedded type's fields
+		// Recursively flatten the embedded type's fields, remembering
+		// which embedded struct they were promoted from
 		embeddedFields := g.flattenFields(embeddedType.Fields, typeMap, seen)
+		for i := range embeddedFields {
+			embeddedFields[i].Via = typeName
+		}
 		result = append(result, embeddedFields...)
 
 		delete(seen, typeName)
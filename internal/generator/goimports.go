@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gogen/internal/model"
+	gogenparser "gogen/internal/parser"
+)
+
+// stdImports maps package names used by generated Go code to standard
+// library import paths.
+var stdImports = map[string]string{
+	"bytes":   "bytes",
+	"errors":  "errors",
+	"fmt":     "fmt",
+	"json":    "encoding/json",
+	"mail":    "net/mail",
+	"maps":    "maps",
+	"math":    "math",
+	"regexp":  "regexp",
+	"slices":  "slices",
+	"sort":    "sort",
+	"strconv": "strconv",
+	"strings": "strings",
+	"time":    "time",
+	"url":     "net/url",
+	"utf8":    "unicode/utf8",
+}
+
+// formatGo fixes the imports of generated Go source and formats it with
+// go/format. Packages referenced but not imported are resolved from the
+// imports of the input file, then from the standard library; unused
+// imports are removed.
+func formatGo(src []byte, inputImports []model.Import) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, goSourceError(src, err)
+	}
+
+	known := make(map[string]string)
+	for name, path := range stdImports {
+		known[name] = path
+	}
+	for _, imp := range inputImports {
+		if name := importName(imp.Alias, imp.Path); name != "_" && name != "." {
+			known[name] = imp.Path
+		}
+	}
+
+	// Keep the imports that are used (and blank or dot imports)
+	used := usedPackages(file)
+	var specs []string
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		alias := ""
+		if spec.Name != nil {
+			alias = spec.Name.Name
+		}
+		name := importName(alias, path)
+		if name != "_" && name != "." && !used[name] {
+			continue
+		}
+		imported[name] = true
+		specs = append(specs, importSpec(alias, path))
+	}
+
+	// Add the missing ones
+	for name := range used {
+		path, ok := known[name]
+		if !ok || imported[name] {
+			continue
+		}
+		alias := ""
+		if gogenparser.PackageName(path) != name {
+			alias = name
+		}
+		specs = append(specs, importSpec(alias, path))
+	}
+	// Standard library imports go first, in a group of their own
+	sort.Slice(specs, func(i, j int) bool {
+		if si, sj := isStdImport(specs[i]), isStdImport(specs[j]); si != sj {
+			return si
+		}
+		return specs[i] < specs[j]
+	})
+
+	// Replace the import declarations with a single one after the package
+	// clause
+	var b bytes.Buffer
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	start := offset(file.Name.End())
+	b.Write(src[:start])
+	switch len(specs) {
+	case 0:
+	case 1:
+		b.WriteString("\n\nimport " + specs[0] + "\n")
+	default:
+		b.WriteString("\n\nimport (\n")
+		for i, spec := range specs {
+			if i > 0 && isStdImport(specs[i-1]) && !isStdImport(spec) {
+				b.WriteString("\n")
+			}
+			b.WriteString("\t" + spec + "\n")
+		}
+		b.WriteString(")\n")
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		b.Write(src[start:offset(gen.Pos())])
+		start = offset(gen.End())
+	}
+	b.Write(src[start:])
+
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, goSourceError(b.Bytes(), err)
+	}
+	return formatted, nil
+}
+
+// usedPackages returns the names of the packages referenced by a file: the
+// qualifiers of selector expressions that do not resolve to a declaration.
+func usedPackages(file *ast.File) map[string]bool {
+	unresolved := make(map[*ast.Ident]bool)
+	for _, ident := range file.Unresolved {
+		unresolved[ident] = true
+	}
+
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && unresolved[ident] {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// importName returns the name an import is referred to by.
+func importName(alias, path string) string {
+	if alias != "" {
+		return alias
+	}
+	return gogenparser.PackageName(path)
+}
+
+// importSpec formats an import spec.
+func importSpec(alias, path string) string {
+	if alias != "" {
+		return alias + " " + strconv.Quote(path)
+	}
+	return strconv.Quote(path)
+}
+
+// isStdImport reports whether an import spec refers to the standard
+// library, whose import paths have no dot in their first element.
+func isStdImport(spec string) bool {
+	path := spec[strings.Index(spec, `"`)+1:]
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,311 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// goValueKind classifies Go values for the validation rules that apply to
+// them.
+type goValueKind int
+
+const (
+	goOther goValueKind = iota
+	goString
+	goNumber
+	goCollection // Slices, arrays and maps (measured by length)
+	goTime
+	goStruct // Struct generated alongside, which has its own methods
+)
+
+// goResolve resolves named non-struct types declared in the input to their
+// underlying type and classifies the result.
+func goResolve(ref model.TypeRef, named map[string]model.Type) (model.TypeRef, goValueKind) {
+	if ref.Kind == model.KindBasic {
+		if typ, ok := named[ref.Name]; ok {
+			if typ.Kind == model.KindStruct {
+				return ref, goStruct
+			}
+			if typ.Underlying != nil {
+				return goResolve(*typ.Underlying, named)
+			}
+		}
+	}
+
+	switch ref.Kind {
+	case model.KindSlice, model.KindArray, model.KindMap:
+		return ref, goCollection
+	case model.KindNamed:
+		if ref.QualifiedName() == "time.Time" {
+			return ref, goTime
+		}
+	case model.KindBasic:
+		switch {
+		case ref.Name == "string":
+			return ref, goString
+		case isNumericName(ref.Name):
+			return ref, goNumber
+		}
+	}
+	return ref, goOther
+}
+
+// goReceiver returns the receiver name for methods of a type.
+func goReceiver(t model.Type) string {
+	for _, r := range t.Name {
+		return string(unicode.ToLower(r))
+	}
+	return "x"
+}
+
+// goValidateOps maps comparison rules to the Go operator that detects a
+// violation and the phrase describing the constraint.
+var goValidateOps = map[string]struct{ op, phrase string }{
+	"min": {"<", "at least"},
+	"max": {">", "at most"},
+	"len": {"!=", "exactly"},
+	"eq":  {"!=", "exactly"},
+	"ne":  {"==", "not"},
+	"gt":  {"<=", "greater than"},
+	"gte": {"<", "at least"},
+	"lt":  {">=", "less than"},
+	"lte": {">", "at most"},
+}
+
+// goValidate returns the body of a Validate() error method for a struct,
+// checking the rules of its validate tags. Structs generated alongside are
+// validated recursively. Violations are collected with errors.Join.
+func goValidate(cfg *config.Config, t model.Type, all []model.Type) string {
+	named := namedTypes(all)
+	recv := goReceiver(t)
+
+	var b strings.Builder
+	b.WriteString("var errs []error\n")
+	for _, f := range t.Fields {
+		if f.IsEmbedded {
+			continue
+		}
+		label := tagOrName(f, cfg.Options.TagKey)
+		expr := recv + "." + f.Name
+		rules := parseValidateTag(f)
+
+		ref := f.Type
+		pointer := ref.Kind == model.KindPointer && ref.Elem != nil
+		if pointer {
+			ref = *ref.Elem
+		}
+		ref, kind := goResolve(ref, named)
+
+		var checks strings.Builder
+		value := expr
+		if pointer {
+			value = "*" + expr
+		}
+		omitempty := false
+		for _, rule := range rules {
+			switch rule.Name {
+			case "required":
+				if pointer {
+					fmt.Fprintf(&b, "if %s == nil {\nerrs = append(errs, errors.New(%q))\n}\n", expr, label+": is required")
+				} else if zero := goZeroCheck(value, kind, ref); zero != "" {
+					fmt.Fprintf(&checks, "if %s {\nerrs = append(errs, errors.New(%q))\n}\n", zero, label+": is required")
+				}
+			case "omitempty":
+				omitempty = true
+			default:
+				goValidateRule(&checks, rule, value, label, kind)
+			}
+		}
+		if kind == goStruct {
+			fmt.Fprintf(&checks, "if err := %s.Validate(); err != nil {\nerrs = append(errs, fmt.Errorf(\"%s: %%w\", err))\n}\n", expr, label)
+		}
+
+		if checks.Len() == 0 {
+			continue
+		}
+		switch {
+		case pointer:
+			fmt.Fprintf(&b, "if %s != nil {\n%s}\n", expr, checks.String())
+		case omitempty && goNonZeroCheck(value, kind, ref) != "":
+			fmt.Fprintf(&b, "if %s {\n%s}\n", goNonZeroCheck(value, kind, ref), checks.String())
+		default:
+			b.WriteString(checks.String())
+		}
+	}
+	b.WriteString("return errors.Join(errs...)")
+	return b.String()
+}
+
+// goZeroCheck returns a condition that holds when a value is its zero value,
+// or "" when there is no meaningful check.
+func goZeroCheck(value string, kind goValueKind, ref model.TypeRef) string {
+	switch kind {
+	case goString:
+		return value + ` == ""`
+	case goNumber:
+		return value + " == 0"
+	case goCollection:
+		return "len(" + value + ") == 0"
+	case goTime:
+		return value + ".IsZero()"
+	}
+	if ref.Kind == model.KindInterface || ref.Name == "any" {
+		return value + " == nil"
+	}
+	return ""
+}
+
+// goNonZeroCheck is the negation of goZeroCheck.
+func goNonZeroCheck(value string, kind goValueKind, ref model.TypeRef) string {
+	switch kind {
+	case goString:
+		return value + ` != ""`
+	case goNumber:
+		return value + " != 0"
+	case goCollection:
+		return "len(" + value + ") != 0"
+	case goTime:
+		return "!" + value + ".IsZero()"
+	}
+	if ref.Kind == model.KindInterface || ref.Name == "any" {
+		return value + " != nil"
+	}
+	return ""
+}
+
+// goValidateRule writes the check for one validate rule. Rules that cannot
+// be checked are recorded as comments in the generated code.
+func goValidateRule(b *strings.Builder, rule ValidateRule, value, label string, kind goValueKind) {
+	fail := func(message string) string {
+		return fmt.Sprintf("errs = append(errs, errors.New(%q))\n", label+": "+message)
+	}
+
+	if spec, ok := goValidateOps[rule.Name]; ok && rule.Value != "" {
+		measure, subject := value, ""
+		switch kind {
+		case goString:
+			measure, subject = "utf8.RuneCountInString(string("+value+"))", "length "
+		case goCollection:
+			measure, subject = "len("+value+")", "size "
+		case goNumber:
+		default:
+			fmt.Fprintf(b, "// validate:%q is not supported for %s\n", rule.Name+"="+rule.Value, label)
+			return
+		}
+		if kind != goNumber {
+			if _, err := strconv.Atoi(rule.Value); err != nil {
+				fmt.Fprintf(b, "// validate:%q is not supported for %s\n", rule.Name+"="+rule.Value, label)
+				return
+			}
+		}
+		fmt.Fprintf(b, "if %s %s %s {\n%s}\n", measure, spec.op, rule.Value, fail(subject+"must be "+spec.phrase+" "+rule.Value))
+		return
+	}
+
+	switch {
+	case rule.Name == "oneof" && (kind == goString || kind == goNumber):
+		options := strings.Fields(rule.Value)
+		cases := make([]string, len(options))
+		for i, opt := range options {
+			if kind == goString {
+				cases[i] = strconv.Quote(opt)
+			} else {
+				cases[i] = opt
+			}
+		}
+		fmt.Fprintf(b, "switch %s {\ncase %s:\ndefault:\n%s}\n", value, strings.Join(cases, ", "), fail("must be one of "+strings.Join(options, ", ")))
+	case rule.Name == "email" && kind == goString:
+		fmt.Fprintf(b, "if _, err := mail.ParseAddress(string(%s)); err != nil {\n%s}\n", value, fail("must be a valid email address"))
+	case (rule.Name == "url" || rule.Name == "uri") && kind == goString:
+		fmt.Fprintf(b, "if _, err := url.ParseRequestURI(string(%s)); err != nil {\n%s}\n", value, fail("must be a valid URL"))
+	default:
+		name := rule.Name
+		if rule.Value != "" {
+			name += "=" + rule.Value
+		}
+		fmt.Fprintf(b, "// validate:%q is not supported for %s\n", name, label)
+	}
+}
+
+// goClone returns the body of a Clone() method for a struct, deep copying
+// pointers, slices and maps. Structs generated alongside are copied with
+// their own Clone method.
+func goClone(t model.Type, all []model.Type) string {
+	named := namedTypes(all)
+	recv := goReceiver(t)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "if %s == nil {\nreturn nil\n}\nclone := *%s\n", recv, recv)
+	for _, f := range t.Fields {
+		if f.IsEmbedded {
+			continue
+		}
+		goCloneInto(&b, "clone."+f.Name, f.Type, named, 1)
+	}
+	b.WriteString("return &clone")
+	return b.String()
+}
+
+// goNeedsClone reports whether values of a type share memory when copied.
+func goNeedsClone(ref model.TypeRef, named map[string]model.Type) bool {
+	ref, kind := goResolve(ref, named)
+	switch {
+	case kind == goStruct:
+		return true
+	case ref.Kind == model.KindPointer, ref.Kind == model.KindSlice, ref.Kind == model.KindMap:
+		return true
+	case ref.Kind == model.KindArray && ref.Elem != nil:
+		return goNeedsClone(*ref.Elem, named)
+	}
+	return false
+}
+
+// goCloneInto writes statements replacing the shallow copy held in dst
+// (an addressable expression) with a deep copy. depth keeps the variables
+// of nested copies apart.
+func goCloneInto(b *strings.Builder, dst string, ref model.TypeRef, named map[string]model.Type, depth int) {
+	if !goNeedsClone(ref, named) {
+		return
+	}
+	resolved, kind := goResolve(ref, named)
+	if kind == goStruct {
+		fmt.Fprintf(b, "%s = *%s.Clone()\n", dst, dst)
+		return
+	}
+
+	i, k, v := fmt.Sprintf("i%d", depth), fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+	switch resolved.Kind {
+	case model.KindPointer:
+		if _, elemKind := goResolve(*resolved.Elem, named); elemKind == goStruct {
+			fmt.Fprintf(b, "%s = %s.Clone()\n", dst, dst)
+			return
+		}
+		fmt.Fprintf(b, "if %s != nil {\n%s := *%s\n", dst, v, dst)
+		goCloneInto(b, v, *resolved.Elem, named, depth+1)
+		fmt.Fprintf(b, "%s = &%s\n}\n", dst, v)
+	case model.KindSlice:
+		fmt.Fprintf(b, "%s = slices.Clone(%s)\n", dst, dst)
+		if resolved.Elem != nil && goNeedsClone(*resolved.Elem, named) {
+			fmt.Fprintf(b, "for %s := range %s {\n", i, dst)
+			goCloneInto(b, dst+"["+i+"]", *resolved.Elem, named, depth+1)
+			b.WriteString("}\n")
+		}
+	case model.KindArray:
+		fmt.Fprintf(b, "for %s := range %s {\n", i, dst)
+		goCloneInto(b, dst+"["+i+"]", *resolved.Elem, named, depth+1)
+		b.WriteString("}\n")
+	case model.KindMap:
+		fmt.Fprintf(b, "%s = maps.Clone(%s)\n", dst, dst)
+		if resolved.Value != nil && goNeedsClone(*resolved.Value, named) {
+			// Map values are not addressable, copy them through a variable
+			fmt.Fprintf(b, "for %s, %s := range %s {\n", k, v, dst)
+			goCloneInto(b, v, *resolved.Value, named, depth+1)
+			fmt.Fprintf(b, "%s[%s] = %s\n}\n", dst, k, v)
+		}
+	}
+}
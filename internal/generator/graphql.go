@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// graphqlBuiltinScalars are the scalars every GraphQL schema provides.
+var graphqlBuiltinScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// gqlType returns the GraphQL type of a field. Fields are non-null unless
+// they are optional; list elements are non-null unless they are pointers.
+// With input set, references to structs use their input type.
+func gqlType(cfg *config.Config, f model.Field, all []model.Type, input bool) string {
+	typ := gqlTypeRef(cfg, f.Type, namedTypes(all), input)
+	if isOptional(f) {
+		return strings.TrimSuffix(typ, "!")
+	}
+	return typ
+}
+
+// gqlTypeRef maps a Go type to a GraphQL type, with "!" for non-null.
+func gqlTypeRef(cfg *config.Config, ref model.TypeRef, named map[string]model.Type, input bool) string {
+	switch ref.Kind {
+	case model.KindPointer:
+		if ref.Elem != nil {
+			return strings.TrimSuffix(gqlTypeRef(cfg, *ref.Elem, named, input), "!")
+		}
+	case model.KindSlice, model.KindArray:
+		if ref.Elem != nil && ref.Raw != "[]byte" {
+			return "[" + gqlTypeRef(cfg, *ref.Elem, named, input) + "]!"
+		}
+	case model.KindBasic:
+		if typ, ok := named[ref.Name]; ok {
+			switch {
+			case typ.Kind == model.KindStruct && input:
+				return typ.Name + "Input!"
+			case typ.Kind != model.KindStruct && enumKind(typ) == "" && typ.Underlying != nil:
+				// GraphQL has no type aliases, use the underlying type
+				return gqlTypeRef(cfg, *typ.Underlying, named, input)
+			}
+		}
+	}
+	if mapped, ok := gqlTypeMapping(cfg, ref); ok {
+		return mapped + "!"
+	}
+	return mapTargetType(cfg, "graphql", ref) + "!"
+}
+
+// Default mappings, to tell them from the configured ones.
+var (
+	defaultTypeMappings    = config.DefaultTypeMappings()
+	defaultGraphQLMappings = config.DefaultTargetMappings()["graphql"]
+)
+
+// gqlNameRe matches GraphQL type names, as opposed to the TypeScript types
+// typeMappings usually holds (e.g., "string", "Record<string, unknown>").
+var gqlNameRe = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*$`)
+
+// gqlTypeMapping returns the configured GraphQL type of a Go type:
+// targetMappings.graphql entries win, then typeMappings entries naming a
+// GraphQL type, which declare custom scalars (e.g., "decimal.Decimal":
+// "Decimal"). Default mappings are left to mapTargetType.
+func gqlTypeMapping(cfg *config.Config, ref model.TypeRef) (string, bool) {
+	candidates := []string{ref.QualifiedName(), ref.Raw, ref.FullName(), ref.Name}
+	for _, name := range candidates {
+		if mapped, ok := cfg.TargetMappings["graphql"][name]; ok && name != "" && mapped != defaultGraphQLMappings[name] {
+			return mapped, true
+		}
+	}
+	for _, name := range candidates {
+		if mapped, ok := cfg.TypeMappings[name]; ok && name != "" && mapped != defaultTypeMappings[name] && gqlNameRe.MatchString(mapped) {
+			return mapped, true
+		}
+	}
+	return "", false
+}
+
+// gqlScalars returns the custom scalars referenced by the generated types,
+// which must be declared in the schema.
+func gqlScalars(cfg *config.Config, types, all []model.Type) []string {
+	named := namedTypes(all)
+	used := make(map[string]bool)
+	for _, t := range types {
+		for _, f := range t.Fields {
+			collectIdents(gqlTypeRef(cfg, f.Type, named, false), used)
+		}
+	}
+
+	var scalars []string
+	for name := range used {
+		if graphqlBuiltinScalars[name] {
+			continue
+		}
+		if _, ok := named[name]; ok {
+			continue
+		}
+		scalars = append(scalars, name)
+	}
+	sort.Strings(scalars)
+	return scalars
+}
+
+// gqlEnumValue returns the GraphQL enum value name for a constant.
+func gqlEnumValue(t model.Type, c model.Constant) string {
+	return strings.ToUpper(snakeCase(constName(t, c)))
+}
+
+// gqlDescription formats a doc comment as a GraphQL description, indented
+// by indent.
+func gqlDescription(doc, indent string) string {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return ""
+	}
+	doc = strings.ReplaceAll(doc, `"""`, `\"""`)
+	if !strings.Contains(doc, "\n") {
+		return indent + `"""` + doc + `"""`
+	}
+	return indent + `"""` + "\n" + formatComment(doc, indent) + "\n" + indent + `"""`
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// kotlinKeywords are hard keywords that must be escaped with backticks.
+var kotlinKeywords = map[string]bool{
+	"as": true, "break": true, "class": true, "continue": true, "do": true, "else": true,
+	"false": true, "for": true, "fun": true, "if": true, "in": true, "interface": true,
+	"is": true, "null": true, "object": true, "package": true, "return": true,
+	"super": true, "this": true, "throw": true, "true": true, "try": true,
+	"typealias": true, "typeof": true, "val": true, "var": true, "when": true, "while": true,
+}
+
+// kotlinImports lists where names used in generated types come from.
+var kotlinImports = map[string]string{
+	"Instant":     "kotlinx.datetime",
+	"JsonElement": "kotlinx.serialization.json",
+}
+
+// ktName returns the camelCase Kotlin property name for a Go field.
+func ktName(f model.Field) string {
+	name := camelCase(f.Name)
+	if kotlinKeywords[name] {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+// ktType returns the Kotlin type of a field, nullable for pointers and
+// omitempty fields.
+func ktType(cfg *config.Config, f model.Field) string {
+	typ := mapTargetType(cfg, "kotlin", f.Type)
+	if isOptional(f) && !strings.HasSuffix(typ, "?") {
+		typ += "?"
+	}
+	return typ
+}
+
+// ktField returns the annotated constructor property for a field, indented
+// for use inside a data class declaration.
+func ktField(cfg *config.Config, f model.Field) string {
+	name := ktName(f)
+	typ := ktType(cfg, f)
+
+	var b strings.Builder
+	if serialName := tagOrName(f, cfg.Options.TagKey); serialName != strings.Trim(name, "`") {
+		fmt.Fprintf(&b, "    @SerialName(%q)\n", serialName)
+	}
+	fmt.Fprintf(&b, "    val %s: %s", name, typ)
+	if strings.HasSuffix(typ, "?") {
+		b.WriteString(" = null")
+	}
+	b.WriteString(",")
+	return b.String()
+}
+
+// ktEnumEntry returns the Kotlin enum entry name for a constant.
+func ktEnumEntry(t model.Type, c model.Constant) string {
+	name := strings.ToUpper(snakeCase(constName(t, c)))
+	if kotlinKeywords[name] {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+// ktEnumPrimitive returns the Kotlin primitive type holding the values of
+// an int or float enum, which its serializer reads and writes.
+func ktEnumPrimitive(cfg *config.Config, t model.Type) string {
+	typ := "Long"
+	if t.Underlying != nil {
+		typ = mapTargetType(cfg, "kotlin", *t.Underlying)
+	}
+	switch typ {
+	case "Byte", "Short", "Int", "Long":
+		if enumKind(t) == "float" {
+			return "Double"
+		}
+		return typ
+	case "Float", "Double":
+		return typ
+	}
+	if enumKind(t) == "float" {
+		return "Double"
+	}
+	return "Long"
+}
+
+// ktEnumLiteral returns a constant value as a Kotlin literal of the enum's
+// primitive type (e.g., "1.0" for a Double, "1.5f" for a Float).
+func ktEnumLiteral(primitive, value string) string {
+	switch primitive {
+	case "Float", "Double":
+		if !strings.ContainsAny(value, ".eE") {
+			value += ".0"
+		}
+		if primitive == "Float" {
+			value += "f"
+		}
+	}
+	return value
+}
+
+// ktImportLines returns the import directives needed by the generated file.
+func ktImportLines(cfg *config.Config, types []model.Type) []string {
+	used := make(map[string]bool)
+	extra := map[string][]string{"kotlinx.serialization": {"SerialName", "Serializable"}}
+	valueEnums := false
+	for _, t := range types {
+		switch enum := enumKind(t); {
+		case enum == "string":
+		case enum != "":
+			valueEnums = true
+		case t.Kind == model.KindStruct:
+			for _, f := range t.Fields {
+				collectIdents(ktType(cfg, f), used)
+			}
+		case t.Underlying != nil:
+			collectIdents(mapTargetType(cfg, "kotlin", *t.Underlying), used)
+		}
+	}
+
+	if valueEnums {
+		// Value serializers of int and float enums
+		extra["kotlinx.serialization"] = append(extra["kotlinx.serialization"], "KSerializer", "SerializationException")
+		extra["kotlinx.serialization.descriptors"] = []string{"PrimitiveKind", "PrimitiveSerialDescriptor", "SerialDescriptor"}
+		extra["kotlinx.serialization.encoding"] = []string{"Decoder", "Encoder"}
+	}
+	return importLines(used, kotlinImports, extra, func(module string, names []string) string {
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = fmt.Sprintf("import %s.%s", module, name)
+		}
+		return strings.Join(lines, "\n")
+	})
+}
@@ -0,0 +1,500 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"gogen/internal/diag"
+	"gogen/internal/model"
+	"gogen/templates"
+)
+
+// builtinFunc describes a function predefined by text/template.
+type builtinFunc struct {
+	min, max int          // Number of arguments; max is -1 if variadic
+	result   reflect.Type // Result type, nil if it depends on the arguments
+}
+
+var (
+	boolType   = reflect.TypeOf(false)
+	intType    = reflect.TypeOf(0)
+	floatType  = reflect.TypeOf(0.0)
+	stringType = reflect.TypeOf("")
+)
+
+// builtinFuncs lists the functions predefined by text/template.
+var builtinFuncs = map[string]builtinFunc{
+	"and":      {1, -1, nil},
+	"or":       {1, -1, nil},
+	"not":      {1, 1, boolType},
+	"len":      {1, 1, intType},
+	"index":    {1, -1, nil},
+	"slice":    {1, -1, nil},
+	"call":     {1, -1, nil},
+	"print":    {0, -1, stringType},
+	"println":  {0, -1, stringType},
+	"printf":   {1, -1, stringType},
+	"html":     {0, -1, stringType},
+	"js":       {0, -1, stringType},
+	"urlquery": {0, -1, stringType},
+	"eq":       {2, -1, boolType},
+	"ne":       {2, 2, boolType},
+	"lt":       {2, 2, boolType},
+	"le":       {2, 2, boolType},
+	"gt":       {2, 2, boolType},
+	"ge":       {2, 2, boolType},
+}
+
+// LintTemplate checks a template file (or built-in template) without
+// executing it: field accesses are checked against TemplateData and the
+// model types reached from it, function calls against the template
+// functions and their arity, and define blocks that are never used are
+// reported. Types the linter cannot follow (e.g., interface values) are
+// not checked.
+func (g *Generator) LintTemplate(path string) ([]*diag.Diagnostic, error) {
+	name := path
+	src, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if file, ok := templates.Lookup(path); ok {
+			name = file
+			src, err = fs.ReadFile(templates.FS, file)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	return g.lint(name, src), nil
+}
+
+// lint checks template source; name is used in positions.
+func (g *Generator) lint(name string, src []byte) []*diag.Diagnostic {
+	funcs := make(map[string]reflect.Type)
+	for fname, fn := range templateFuncs(g.config) {
+		funcs[fname] = reflect.TypeOf(fn)
+	}
+	for fname, fn := range g.stateFuncs() {
+		funcs[fname] = reflect.TypeOf(fn)
+	}
+
+	l := &linter{
+		funcs:   funcs,
+		trees:   make(map[string]*parse.Tree),
+		used:    make(map[string]bool),
+		visited: make(map[string]map[reflect.Type]bool),
+	}
+	root := parse.New(name)
+	root.Mode = parse.SkipFuncCheck
+	if _, err := root.Parse(string(src), "", "", l.trees); err != nil {
+		return []*diag.Diagnostic{templateSyntaxError(name, err)}
+	}
+
+	data := reflect.TypeOf(&TemplateData{})
+	l.walkTree(name, data)
+
+	// Defines never called are still checked, with an unknown dot
+	var unused []string
+	for tname := range l.trees {
+		if tname != name && !l.used[tname] {
+			unused = append(unused, tname)
+		}
+	}
+	sort.Strings(unused)
+	for _, tname := range unused {
+		tree := l.trees[tname]
+		l.report(tree, tree.Root, diag.Warning, diag.CodeUnusedDefine, "template %q is defined but never used", tname)
+		l.walkTree(tname, nil)
+	}
+
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		a, b := l.diags[i].Pos, l.diags[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.diags
+}
+
+// templateSyntaxError converts a template parse error, formatted as
+// "template: name:line: message", into a diagnostic.
+func templateSyntaxError(name string, err error) *diag.Diagnostic {
+	msg := strings.TrimPrefix(err.Error(), "template: ")
+	pos := model.Position{File: name}
+	if rest, ok := strings.CutPrefix(msg, name+":"); ok {
+		if i := strings.Index(rest, ": "); i > 0 {
+			if line, err := strconv.Atoi(rest[:i]); err == nil {
+				pos.Line = line
+				msg = rest[i+2:]
+			}
+		}
+	}
+	return &diag.Diagnostic{Pos: pos, Severity: diag.Error, Code: diag.CodeSyntax, Message: msg}
+}
+
+// linter walks template parse trees, tracking the type of dot and of
+// variables. A nil type means the type is unknown, and is not checked.
+type linter struct {
+	funcs   map[string]reflect.Type
+	trees   map[string]*parse.Tree
+	used    map[string]bool                  // Templates called with {{ template }} or {{ block }}
+	visited map[string]map[reflect.Type]bool // Templates already checked, per dot type
+	diags   []*diag.Diagnostic
+}
+
+// vars maps variable names to their types within a scope.
+type vars map[string]reflect.Type
+
+func (v vars) clone() vars {
+	c := make(vars, len(v))
+	for k, t := range v {
+		c[k] = t
+	}
+	return c
+}
+
+// walkTree checks a template with the given type of dot, once per type.
+func (l *linter) walkTree(name string, dot reflect.Type) {
+	tree := l.trees[name]
+	if tree == nil || tree.Root == nil {
+		return
+	}
+	if l.visited[name] == nil {
+		l.visited[name] = make(map[reflect.Type]bool)
+	}
+	if l.visited[name][dot] {
+		return
+	}
+	l.visited[name][dot] = true
+	l.walk(tree, tree.Root, dot, vars{"$": dot})
+}
+
+// report records a diagnostic at a node.
+func (l *linter) report(tree *parse.Tree, node parse.Node, severity diag.Severity, code, format string, args ...any) {
+	l.diags = append(l.diags, &diag.Diagnostic{
+		Pos:      nodePosition(tree, node),
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// nodePosition returns the position of a node in its template, from the
+// "name:line:col" location text/template reports.
+func nodePosition(tree *parse.Tree, node parse.Node) model.Position {
+	loc, _ := tree.ErrorContext(node)
+	pos := model.Position{File: tree.ParseName}
+	rest := strings.TrimPrefix(loc, tree.ParseName+":")
+	if line, col, ok := strings.Cut(rest, ":"); ok {
+		pos.Line, _ = strconv.Atoi(line)
+		pos.Column, _ = strconv.Atoi(col)
+	}
+	return pos
+}
+
+// walk checks a node and its children.
+func (l *linter) walk(tree *parse.Tree, node parse.Node, dot reflect.Type, v vars) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			l.walk(tree, child, dot, v)
+		}
+	case *parse.ActionNode:
+		l.pipe(tree, n.Pipe, dot, v)
+	case *parse.IfNode:
+		scope := v.clone()
+		l.pipe(tree, n.Pipe, dot, scope)
+		l.walk(tree, n.List, dot, scope.clone())
+		l.walk(tree, n.ElseList, dot, scope.clone())
+	case *parse.WithNode:
+		scope := v.clone()
+		typ := l.pipe(tree, n.Pipe, dot, scope)
+		l.walk(tree, n.List, typ, scope.clone())
+		l.walk(tree, n.ElseList, dot, scope.clone())
+	case *parse.RangeNode:
+		scope := v.clone()
+		typ := l.pipeType(tree, n.Pipe, dot, scope)
+		key, elem := rangeTypes(typ)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			scope[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			scope[n.Pipe.Decl[0].Ident[0]] = key
+			scope[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		l.walk(tree, n.List, elem, scope.clone())
+		l.walk(tree, n.ElseList, dot, scope.clone())
+	case *parse.TemplateNode:
+		l.used[n.Name] = true
+		var typ reflect.Type
+		if n.Pipe != nil {
+			typ = l.pipe(tree, n.Pipe, dot, v)
+		}
+		if l.trees[n.Name] == nil {
+			l.report(tree, n, diag.Error, diag.CodeUndefinedTemplate, "template %q is not defined", n.Name)
+			return
+		}
+		l.walkTree(n.Name, typ)
+	}
+}
+
+// pipe checks a pipeline, declares its variables, and returns its type.
+func (l *linter) pipe(tree *parse.Tree, p *parse.PipeNode, dot reflect.Type, v vars) reflect.Type {
+	typ := l.pipeType(tree, p, dot, v)
+	for _, decl := range p.Decl {
+		v[decl.Ident[0]] = typ
+	}
+	return typ
+}
+
+// pipeType checks the commands of a pipeline and returns its type.
+func (l *linter) pipeType(tree *parse.Tree, p *parse.PipeNode, dot reflect.Type, v vars) reflect.Type {
+	if p == nil {
+		return nil
+	}
+	var typ reflect.Type
+	for i, cmd := range p.Cmds {
+		typ = l.command(tree, cmd, dot, v, i > 0, typ)
+	}
+	return typ
+}
+
+// command checks a command and returns its type. In a pipeline, all
+// commands but the first get the result of the previous one as their last
+// argument.
+func (l *linter) command(tree *parse.Tree, cmd *parse.CommandNode, dot reflect.Type, v vars, piped bool, pipedType reflect.Type) reflect.Type {
+	nargs := len(cmd.Args) - 1
+	if piped {
+		nargs++
+	}
+	var argTypes []reflect.Type
+	for _, arg := range cmd.Args[1:] {
+		argTypes = append(argTypes, l.arg(tree, arg, dot, v))
+	}
+	if piped {
+		argTypes = append(argTypes, pipedType)
+	}
+
+	switch n := cmd.Args[0].(type) {
+	case *parse.FieldNode:
+		return l.fields(tree, n, dot, n.Ident, nargs)
+	case *parse.ChainNode:
+		return l.fields(tree, n, l.arg(tree, n.Node, dot, v), n.Field, nargs)
+	case *parse.VariableNode:
+		return l.fields(tree, n, v[n.Ident[0]], n.Ident[1:], nargs)
+	case *parse.IdentifierNode:
+		return l.call(tree, n, argTypes)
+	}
+	if nargs > 0 {
+		l.report(tree, cmd, diag.Error, diag.CodeArity, "can't give argument to non-function %s", cmd.Args[0])
+	}
+	return l.arg(tree, cmd.Args[0], dot, v)
+}
+
+// arg checks an operand and returns its type.
+func (l *linter) arg(tree *parse.Tree, node parse.Node, dot reflect.Type, v vars) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return l.fields(tree, n, dot, n.Ident, 0)
+	case *parse.ChainNode:
+		return l.fields(tree, n, l.arg(tree, n.Node, dot, v), n.Field, 0)
+	case *parse.VariableNode:
+		return l.fields(tree, n, v[n.Ident[0]], n.Ident[1:], 0)
+	case *parse.IdentifierNode:
+		return l.call(tree, n, nil)
+	case *parse.PipeNode:
+		return l.pipe(tree, n, dot, v.clone())
+	case *parse.BoolNode:
+		return boolType
+	case *parse.StringNode:
+		return stringType
+	case *parse.NumberNode:
+		switch {
+		case n.IsInt:
+			return intType
+		case n.IsFloat:
+			return floatType
+		}
+	}
+	return nil
+}
+
+// fields checks a chain of field or method accesses on a value of type
+// typ, the last of which gets nargs arguments, and returns the type of the
+// result.
+func (l *linter) fields(tree *parse.Tree, node parse.Node, typ reflect.Type, idents []string, nargs int) reflect.Type {
+	for i, name := range idents {
+		if typ == nil {
+			return nil
+		}
+		args := 0
+		if i == len(idents)-1 {
+			args = nargs
+		}
+
+		if m, ok := lookupMethod(typ, name); ok {
+			in := m.NumIn()
+			if m.IsVariadic() && args < in-1 || !m.IsVariadic() && args != in {
+				l.report(tree, node, diag.Error, diag.CodeArity, "method %s.%s takes %s, got %d", typ, name, arity(in, m.IsVariadic()), args)
+			}
+			typ = resultType(m)
+			continue
+		}
+
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			f, ok := typ.FieldByName(name)
+			if !ok || !f.IsExported() {
+				l.report(tree, node, diag.Error, diag.CodeUnknownField, "%s has no field or method %s", typ, name)
+				return nil
+			}
+			if args > 0 {
+				l.report(tree, node, diag.Error, diag.CodeArity, "%s.%s is a field, not a method, and takes no arguments", typ, name)
+			}
+			typ = f.Type
+		case reflect.Map:
+			if typ.Key().Kind() != reflect.String {
+				l.report(tree, node, diag.Error, diag.CodeUnknownField, "can't use .%s on %s, which has non-string keys", name, typ)
+				return nil
+			}
+			typ = typ.Elem()
+		case reflect.Interface:
+			return nil
+		default:
+			l.report(tree, node, diag.Error, diag.CodeUnknownField, "can't evaluate field %s in type %s", name, typ)
+			return nil
+		}
+	}
+	return known(typ)
+}
+
+// call checks a function call and returns the type of its result.
+func (l *linter) call(tree *parse.Tree, node *parse.IdentifierNode, args []reflect.Type) reflect.Type {
+	name := node.Ident
+	if fn, ok := l.funcs[name]; ok {
+		in := fn.NumIn()
+		if fn.IsVariadic() && len(args) < in-1 || !fn.IsVariadic() && len(args) != in {
+			l.report(tree, node, diag.Error, diag.CodeArity, "function %s takes %s, got %d", name, arity(in, fn.IsVariadic()), len(args))
+		}
+		return resultType(fn)
+	}
+
+	b, ok := builtinFuncs[name]
+	if !ok {
+		l.report(tree, node, diag.Error, diag.CodeUnknownFunc, "function %q is not defined", name)
+		return nil
+	}
+	if len(args) < b.min || b.max >= 0 && len(args) > b.max {
+		want := fmt.Sprintf("at least %d argument(s)", b.min)
+		if b.min == b.max {
+			want = fmt.Sprintf("%d argument(s)", b.min)
+		}
+		l.report(tree, node, diag.Error, diag.CodeArity, "function %s takes %s, got %d", name, want, len(args))
+	}
+	switch name {
+	case "index":
+		if len(args) == 0 {
+			return nil
+		}
+		typ := args[0]
+		for range args[1:] {
+			_, typ = rangeTypes(typ)
+		}
+		return typ
+	case "slice":
+		if len(args) > 0 {
+			return known(args[0])
+		}
+	}
+	return b.result
+}
+
+// lookupMethod finds a method callable on a value of type typ, including
+// methods with pointer receivers (template values are often addressable).
+func lookupMethod(typ reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := typ.MethodByName(name); ok {
+		if typ.Kind() == reflect.Interface {
+			return m.Type, true
+		}
+		return methodFunc(m.Type), true
+	}
+	if typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		if m, ok := reflect.PointerTo(typ).MethodByName(name); ok {
+			return methodFunc(m.Type), true
+		}
+	}
+	return nil, false
+}
+
+// methodFunc returns the type of a method without its receiver.
+func methodFunc(m reflect.Type) reflect.Type {
+	in := make([]reflect.Type, 0, m.NumIn()-1)
+	for i := 1; i < m.NumIn(); i++ {
+		in = append(in, m.In(i))
+	}
+	out := make([]reflect.Type, 0, m.NumOut())
+	for i := 0; i < m.NumOut(); i++ {
+		out = append(out, m.Out(i))
+	}
+	return reflect.FuncOf(in, out, m.IsVariadic())
+}
+
+// resultType returns the type of the value a function returns to a
+// template (its first result).
+func resultType(fn reflect.Type) reflect.Type {
+	if fn.NumOut() == 0 {
+		return nil
+	}
+	return known(fn.Out(0))
+}
+
+// known returns typ, or nil for interface types, whose dynamic type is
+// only known at execution.
+func known(typ reflect.Type) reflect.Type {
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return nil
+	}
+	return typ
+}
+
+// rangeTypes returns the key and element types of ranging over typ.
+func rangeTypes(typ reflect.Type) (key, elem reflect.Type) {
+	if typ == nil {
+		return nil, nil
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return intType, known(typ.Elem())
+	case reflect.Map:
+		return known(typ.Key()), known(typ.Elem())
+	case reflect.Chan:
+		return nil, known(typ.Elem())
+	case reflect.Int, reflect.Int64:
+		return typ, typ
+	}
+	return nil, nil
+}
+
+// arity describes a number of arguments.
+func arity(n int, variadic bool) string {
+	if variadic {
+		return fmt.Sprintf("at least %d argument(s)", n-1)
+	}
+	return fmt.Sprintf("%d argument(s)", n)
+}
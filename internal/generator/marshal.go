@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"gogen/internal/diag"
+	"gogen/internal/model"
+)
+
+// marshalShapes maps the shapes of the //gogen:marshal directive to the
+// Go type generated for them ("" keeps the declared shape).
+var marshalShapes = map[string]string{
+	"string":  "string",
+	"number":  "float64",
+	"boolean": "bool",
+	"unknown": "any",
+	"struct":  "",
+}
+
+// MarshalShape returns the shape a type serializes as because of its
+// marshaling methods: "string", "number", "boolean" or "unknown", or ""
+// if it serializes as declared. The //gogen:marshal directive sets the
+// shape; otherwise text marshalers are strings and the output of
+// MarshalJSON is unknown. Stringers keep their shape, since encoding/json
+// ignores String, and so do enums, since their constants give their values.
+func MarshalShape(t model.Type) (string, error) {
+	if shape, ok := t.Directives["marshal"]; ok {
+		if _, ok := marshalShapes[shape]; !ok {
+			return "", diag.Errorf(t.Pos, diag.CodeDirective, "unknown //gogen:marshal shape %q for %s (want string, number, boolean, unknown or struct)", shape, t.Name)
+		}
+		if shape == "struct" {
+			return "", nil
+		}
+		return shape, nil
+	}
+	if len(t.Constants) > 0 {
+		return "", nil
+	}
+	switch t.Marshaler {
+	case model.JSONMarshaler:
+		return "unknown", nil
+	case model.TextMarshaler:
+		return "string", nil
+	}
+	return "", nil
+}
+
+// applyMarshalers replaces the declared shape of types that serialize
+// through marshaling methods by a named type of their shape, so that every
+// template renders them as such. Go and SQL outputs keep the declared
+// shapes, since they describe the Go types and their storage.
+func (g *Generator) applyMarshalers(types []model.Type) ([]model.Type, error) {
+	if g.keepsDeclaredShapes() {
+		return types, nil
+	}
+	for i, t := range types {
+		shape, err := MarshalShape(t)
+		if err != nil {
+			return nil, err
+		}
+		if shape == "" {
+			if t.Marshaler == model.Stringer && !t.Directives.Has("marshal") && len(t.Constants) == 0 {
+				g.diags.Warnf(t.Pos, diag.CodeMarshaler, "%s implements fmt.Stringer, which encoding/json ignores: generating its declared shape (add //gogen:marshal string if it serializes as a string)", t.Name)
+			}
+			continue
+		}
+		name := marshalShapes[shape]
+		types[i].Kind = model.KindNamed
+		types[i].Underlying = &model.TypeRef{Kind: model.KindBasic, Name: name, Raw: name}
+		types[i].Fields = nil
+		types[i].Constants = nil
+	}
+	return types, nil
+}
@@ -0,0 +1,374 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gogen/internal/config"
+	"gogen/internal/diag"
+	"gogen/internal/model"
+)
+
+// protoImports lists the well-known type files needed by mapped types.
+var protoImports = map[string]string{
+	"google.protobuf.Timestamp": "google/protobuf/timestamp.proto",
+	"google.protobuf.Duration":  "google/protobuf/duration.proto",
+	"google.protobuf.Value":     "google/protobuf/struct.proto",
+	"google.protobuf.Struct":    "google/protobuf/struct.proto",
+	"google.protobuf.Any":       "google/protobuf/any.proto",
+}
+
+// protoLock persists proto field and enum value numbers across runs, so a
+// number is never reassigned once it has been handed out.
+type protoLock struct {
+	Messages map[string]*protoLockEntry `json:"messages"`
+	Enums    map[string]*protoLockEntry `json:"enums"`
+
+	mu    sync.Mutex
+	path  string
+	dirty bool
+}
+
+// protoLockEntry holds the numbers assigned within one message or enum.
+type protoLockEntry struct {
+	Numbers map[string]int `json:"numbers"`
+	Next    int            `json:"next"`
+}
+
+// protoField is a message field ready to be rendered.
+type protoField struct {
+	Label  string // "repeated", "optional" or empty
+	Type   string // Proto type (e.g., "string", "google.protobuf.Timestamp")
+	Name   string // snake_case field name
+	Number int    // Field number
+	JSON   string // Original JSON name
+	Doc    string // Documentation comment
+}
+
+// protoReserved lists the numbers and names of removed fields.
+type protoReserved struct {
+	Numbers []int
+	Names   []string
+}
+
+// loadProtoLock reads a lock file. A missing file yields an empty lock, and
+// an empty path yields an in-memory lock that is never saved.
+func loadProtoLock(path string) (*protoLock, error) {
+	lock := &protoLock{
+		Messages: make(map[string]*protoLockEntry),
+		Enums:    make(map[string]*protoLockEntry),
+		path:     path,
+	}
+	if path == "" {
+		return lock, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading proto lock file: %w", err)
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("parsing proto lock file %s: %w", path, err)
+	}
+	if lock.Messages == nil {
+		lock.Messages = make(map[string]*protoLockEntry)
+	}
+	if lock.Enums == nil {
+		lock.Enums = make(map[string]*protoLockEntry)
+	}
+	return lock, nil
+}
+
+// ProtoLocks shares lock files between the generators of a run: each file is
+// loaded once, and the targets numbering messages in it concurrently see
+// each other's numbers instead of overwriting them when saving.
+type ProtoLocks struct {
+	mu    sync.Mutex
+	locks map[string]*protoLock
+}
+
+// NewProtoLocks creates an empty set of lock files.
+func NewProtoLocks() *ProtoLocks {
+	return &ProtoLocks{locks: make(map[string]*protoLock)}
+}
+
+// load returns the lock of path, reading the file the first time.
+func (s *ProtoLocks) load(path string) (*protoLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lock, ok := s.locks[path]; ok {
+		return lock, nil
+	}
+	lock, err := loadProtoLock(path)
+	if err != nil {
+		return nil, err
+	}
+	s.locks[path] = lock
+	return lock, nil
+}
+
+// Save writes the lock files in which numbers were assigned.
+func (s *ProtoLocks) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths := make([]string, 0, len(s.locks))
+	for path := range s.locks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := s.locks[path].save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save writes the lock file if numbers were assigned since it was loaded.
+func (l *protoLock) save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" || !l.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding proto lock file: %w", err)
+	}
+	if err := os.WriteFile(l.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing proto lock file: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// number returns the number of name within scope, assigning the next free
+// number (starting at first) when the name has not been seen before.
+func (l *protoLock) number(entries map[string]*protoLockEntry, scope, name string, first int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := entries[scope]
+	if !ok {
+		entry = &protoLockEntry{Numbers: make(map[string]int), Next: first}
+		entries[scope] = entry
+	}
+	if n, ok := entry.Numbers[name]; ok {
+		return n
+	}
+	n := entry.Next
+	entry.Numbers[name] = n
+	entry.Next++
+	l.dirty = true
+	return n
+}
+
+// reserved returns the locked numbers of scope whose names are not current.
+func (l *protoLock) reserved(entries map[string]*protoLockEntry, scope string, current map[string]bool) protoReserved {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var r protoReserved
+	entry, ok := entries[scope]
+	if !ok {
+		return r
+	}
+	for name, n := range entry.Numbers {
+		if !current[name] {
+			r.Numbers = append(r.Numbers, n)
+			r.Names = append(r.Names, name)
+		}
+	}
+	sort.Ints(r.Numbers)
+	sort.Strings(r.Names)
+	return r
+}
+
+// protoFieldsOf returns the fields of a message, numbered through the lock.
+// Named types without constants are resolved to their underlying type, as
+// proto has no type aliases.
+func protoFieldsOf(cfg *config.Config, lock *protoLock, diags *diag.Collector, t model.Type, all []model.Type) []protoField {
+	named := make(map[string]model.Type, len(all))
+	for _, typ := range all {
+		named[typ.Name] = typ
+	}
+
+	var fields []protoField
+	for _, f := range t.Fields {
+		if !f.IsExported || tagName(f, cfg.Options.TagKey) == "-" {
+			continue
+		}
+
+		label, typ, exact := protoFieldType(cfg, f.Type, named)
+		if !exact {
+			diags.Warnf(f.Pos, diag.CodeUnsupportedType, "field %s: proto cannot represent %s, generating %s", f.Name, f.Type.Raw, strings.TrimSpace(label+" "+typ))
+		}
+
+		name := snakeCase(f.Name)
+		fields = append(fields, protoField{
+			Label:  label,
+			Type:   typ,
+			Name:   name,
+			Number: lock.number(lock.Messages, t.Name, name, 1),
+			JSON:   tagOrName(f, cfg.Options.TagKey),
+			Doc:    f.Doc,
+		})
+	}
+	return fields
+}
+
+// protoFieldType returns the label and proto type of a field of Go type
+// ref. Pointers are optional fields, or plain elements of repeated fields
+// and maps. exact is false when proto cannot represent the type (e.g.,
+// repeated maps), and typ is then the closest well-known type.
+func protoFieldType(cfg *config.Config, ref model.TypeRef, named map[string]model.Type) (label, typ string, exact bool) {
+	for ref.Kind == model.KindPointer && ref.Elem != nil {
+		label = "optional"
+		ref = *ref.Elem
+	}
+	switch {
+	case isProtoRepeated(ref):
+		typ, exact = protoElemType(cfg, *ref.Elem, named)
+		return "repeated", typ, exact
+	case ref.Kind == model.KindMap && ref.Value != nil:
+		// Map fields cannot be optional
+		_, exact = protoElemType(cfg, *ref.Value, named)
+		return "", protoType(cfg, ref, named), exact
+	}
+	return label, protoType(cfg, ref, named), true
+}
+
+// isProtoRepeated reports whether a Go type is a repeated field in proto.
+func isProtoRepeated(ref model.TypeRef) bool {
+	return (ref.Kind == model.KindSlice || ref.Kind == model.KindArray) && ref.Raw != "[]byte" && ref.Elem != nil
+}
+
+// protoType maps a (non-repeated) Go type to a proto type.
+func protoType(cfg *config.Config, ref model.TypeRef, named map[string]model.Type) string {
+	if ref.Kind == model.KindBasic {
+		if typ, ok := named[ref.Name]; ok && typ.Kind != model.KindStruct && enumKind(typ) == "" && typ.Underlying != nil {
+			return protoType(cfg, *typ.Underlying, named)
+		}
+	}
+
+	switch ref.Kind {
+	case model.KindPointer:
+		if ref.Elem != nil {
+			return protoType(cfg, *ref.Elem, named)
+		}
+	case model.KindSlice, model.KindArray:
+		if ref.Raw != "[]byte" {
+			// Nested repeated fields cannot be expressed directly
+			return "google.protobuf.Value"
+		}
+	case model.KindMap:
+		if ref.Key != nil && ref.Value != nil {
+			value, _ := protoElemType(cfg, *ref.Value, named)
+			return fmt.Sprintf("map<%s, %s>", protoType(cfg, *ref.Key, named), value)
+		}
+	}
+	return mapTargetType(cfg, "proto", ref)
+}
+
+// protoStruct is the proto type of maps nested in repeated fields or maps.
+const protoStruct = "google.protobuf.Struct"
+
+// protoElemType maps the element type of a repeated field or the value type
+// of a map, where pointers are their element type. Proto has no repeated
+// maps, maps of maps or nested repeated fields, so maps there become
+// google.protobuf.Struct and repeated types google.protobuf.Value, and
+// exact is false.
+func protoElemType(cfg *config.Config, ref model.TypeRef, named map[string]model.Type) (typ string, exact bool) {
+	for ref.Kind == model.KindPointer && ref.Elem != nil {
+		ref = *ref.Elem
+	}
+	typ = protoType(cfg, ref, named)
+	switch {
+	case strings.HasPrefix(typ, "map<"):
+		return protoStruct, false
+	case isProtoRepeated(ref):
+		return typ, false
+	}
+	return typ, true
+}
+
+// protoEnumValue is an enum value ready to be rendered.
+type protoEnumValue struct {
+	Name   string
+	Number int
+	Doc    string
+}
+
+// protoEnumValuesOf returns the values of an enum generated from typed
+// constants, numbered through the lock after the _UNSPECIFIED zero value.
+func protoEnumValuesOf(lock *protoLock, t model.Type) []protoEnumValue {
+	prefix := strings.ToUpper(snakeCase(t.Name))
+	values := []protoEnumValue{{Name: prefix + "_UNSPECIFIED", Number: 0}}
+	for _, c := range t.Constants {
+		name := prefix + "_" + strings.ToUpper(snakeCase(constName(t, c)))
+		values = append(values, protoEnumValue{
+			Name:   name,
+			Number: lock.number(lock.Enums, t.Name, name, 1),
+			Doc:    c.Doc,
+		})
+	}
+	return values
+}
+
+// protoReservedOf returns the removed field numbers of a message or enum.
+func protoReservedOf(cfg *config.Config, lock *protoLock, t model.Type) protoReserved {
+	current := make(map[string]bool)
+	if enumKind(t) != "" {
+		prefix := strings.ToUpper(snakeCase(t.Name))
+		for _, c := range t.Constants {
+			current[prefix+"_"+strings.ToUpper(snakeCase(constName(t, c)))] = true
+		}
+		return lock.reserved(lock.Enums, t.Name, current)
+	}
+	for _, f := range t.Fields {
+		if f.IsExported && tagName(f, cfg.Options.TagKey) != "-" {
+			current[snakeCase(f.Name)] = true
+		}
+	}
+	return lock.reserved(lock.Messages, t.Name, current)
+}
+
+// protoImportLines returns the import statements for well-known types.
+func protoImportLines(cfg *config.Config, types []model.Type) []string {
+	named := make(map[string]model.Type, len(types))
+	for _, typ := range types {
+		named[typ.Name] = typ
+	}
+
+	files := make(map[string]bool)
+	for _, t := range types {
+		if t.Kind != model.KindStruct {
+			continue
+		}
+		for _, f := range t.Fields {
+			_, typ, _ := protoFieldType(cfg, f.Type, named)
+			for wellKnown, file := range protoImports {
+				if strings.Contains(typ, wellKnown) {
+					files[file] = true
+				}
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(files))
+	for file := range files {
+		lines = append(lines, fmt.Sprintf("import %q;", file))
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,181 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// pythonKeywords are reserved words that cannot be used as attribute names.
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true,
+	"def": true, "del": true, "elif": true, "else": true, "except": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true, "in": true,
+	"is": true, "lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true,
+	"raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// pythonImports lists where names used in generated annotations come from.
+var pythonImports = map[string]string{
+	"Any":      "typing",
+	"Dict":     "typing",
+	"List":     "typing",
+	"Literal":  "typing",
+	"Optional": "typing",
+	"Tuple":    "typing",
+	"datetime": "datetime",
+	"Decimal":  "decimal",
+	"UUID":     "uuid",
+	"Enum":     "enum",
+	"IntEnum":  "enum",
+	"AnyUrl":   "pydantic",
+	"EmailStr": "pydantic",
+}
+
+// pyName returns a snake_case Python attribute name for a Go field.
+func pyName(f model.Field) string {
+	name := snakeCase(f.Name)
+	if pythonKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// pyType returns the Python annotation for a field, taking validate tag
+// rules that change the type (email, url, oneof) into account.
+func pyType(cfg *config.Config, f model.Field) string {
+	typ := mapTargetType(cfg, "python", f.Type)
+
+	if f.Type.Kind == model.KindBasic && f.Type.Name == "string" {
+		for _, rule := range parseValidateTag(f) {
+			switch rule.Name {
+			case "email":
+				typ = "EmailStr"
+			case "url", "uri":
+				typ = "AnyUrl"
+			case "uuid", "uuid4":
+				typ = "UUID"
+			case "oneof":
+				var values []string
+				for _, v := range strings.Fields(rule.Value) {
+					values = append(values, fmt.Sprintf("%q", v))
+				}
+				typ = "Literal[" + strings.Join(values, ", ") + "]"
+			}
+		}
+	}
+
+	if isOptional(f) && !strings.HasPrefix(typ, "Optional[") {
+		typ = "Optional[" + typ + "]"
+	}
+	return typ
+}
+
+// pyField returns a complete Pydantic field declaration
+// (e.g., `created_at: datetime = Field(alias="createdAt")`).
+func pyField(cfg *config.Config, f model.Field) string {
+	name := pyName(f)
+	typ := pyType(cfg, f)
+
+	var args []string
+	if isOptional(f) {
+		args = append(args, "default=None")
+	}
+	if alias := tagOrName(f, cfg.Options.TagKey); alias != name {
+		args = append(args, fmt.Sprintf("alias=%q", alias))
+	}
+	args = append(args, pyConstraints(f)...)
+
+	switch {
+	case len(args) == 0:
+		return fmt.Sprintf("%s: %s", name, typ)
+	case len(args) == 1 && args[0] == "default=None":
+		return fmt.Sprintf("%s: %s = None", name, typ)
+	default:
+		return fmt.Sprintf("%s: %s = Field(%s)", name, typ, strings.Join(args, ", "))
+	}
+}
+
+// pyConstraints converts validate tag rules into Pydantic Field arguments.
+func pyConstraints(f model.Field) []string {
+	t := f.Type
+	if t.Kind == model.KindPointer && t.Elem != nil {
+		t = *t.Elem
+	}
+	isNumber := t.Kind == model.KindBasic && isNumericName(t.Name)
+
+	var args []string
+	for _, rule := range parseValidateTag(f) {
+		if rule.Value == "" {
+			continue
+		}
+		switch rule.Name {
+		case "min":
+			args = append(args, ternary(isNumber, "ge=", "min_length=")+rule.Value)
+		case "max":
+			args = append(args, ternary(isNumber, "le=", "max_length=")+rule.Value)
+		case "len":
+			if !isNumber {
+				args = append(args, "min_length="+rule.Value, "max_length="+rule.Value)
+			}
+		case "gt", "gte", "lt", "lte":
+			name := map[string]string{"gt": "gt", "gte": "ge", "lt": "lt", "lte": "le"}[rule.Name]
+			args = append(args, name+"="+rule.Value)
+		}
+	}
+	return args
+}
+
+// isNumericName reports whether a Go basic type name is numeric.
+func isNumericName(name string) bool {
+	return strings.HasPrefix(name, "int") || strings.HasPrefix(name, "uint") ||
+		strings.HasPrefix(name, "float") || strings.HasPrefix(name, "complex") ||
+		name == "byte" || name == "rune"
+}
+
+// pyEnumBase returns the base classes for a type with typed constants
+// ("str, Enum" or "IntEnum"), or "" if it should not become an enum.
+func pyEnumBase(t model.Type) string {
+	switch enumKind(t) {
+	case "string":
+		return "str, Enum"
+	case "int":
+		return "IntEnum"
+	default:
+		return ""
+	}
+}
+
+// pyEnumMember returns the Python enum member name for a constant.
+func pyEnumMember(t model.Type, c model.Constant) string {
+	name := strings.ToUpper(snakeCase(constName(t, c)))
+	if name == "" || pythonKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
+// pyImports returns the import statements needed by the generated module.
+func pyImports(cfg *config.Config, types []model.Type) []string {
+	used := make(map[string]bool)
+	for _, t := range types {
+		switch {
+		case pyEnumBase(t) != "":
+			collectIdents(pyEnumBase(t), used)
+		case t.Kind == model.KindStruct:
+			for _, f := range t.Fields {
+				collectIdents(pyType(cfg, f), used)
+			}
+		case t.Underlying != nil:
+			collectIdents(mapTargetType(cfg, "python", *t.Underlying), used)
+		}
+	}
+
+	extra := map[string][]string{"pydantic": {"BaseModel", "ConfigDict", "Field"}}
+	return importLines(used, pythonImports, extra, func(module string, names []string) string {
+		return fmt.Sprintf("from %s import %s", module, strings.Join(names, ", "))
+	})
+}
@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Region markers delimit hand-written code kept across regenerations:
+//
+//	// gogen:begin custom
+//	...
+//	// gogen:end custom
+const (
+	regionBegin = "gogen:begin "
+	regionEnd   = "gogen:end "
+)
+
+// commentPrefixes holds the line comment prefix of output languages, keyed
+// by output extension. Other languages use "//".
+var commentPrefixes = map[string]string{
+	".py":      "#",
+	".yaml":    "#",
+	".yml":     "#",
+	".graphql": "#",
+	".gql":     "#",
+	".sh":      "#",
+	".toml":    "#",
+	".sql":     "--",
+}
+
+// regions holds the protected regions of an existing output file and
+// records which ones the template declares.
+type regions struct {
+	path     string
+	content  map[string]string // Region name -> content (without markers)
+	declared map[string]bool   // Regions declared by any template execution
+	current  map[string]bool   // Regions declared by the current execution
+}
+
+// reset starts a new template execution, which may declare the regions of
+// the previous ones again (e.g., once per type with --per-type; regions
+// named after the type keep distinct contents).
+func (r *regions) reset() {
+	r.current = make(map[string]bool)
+}
+
+// loadRegions reads the protected regions of an existing output file. A
+// missing file (or no path) has no regions.
+func loadRegions(path string) (*regions, error) {
+	r := &regions{
+		path:     path,
+		content:  make(map[string]string),
+		declared: make(map[string]bool),
+		current:  make(map[string]bool),
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading existing output: %w", err)
+	}
+
+	var name string
+	var body []string
+	start := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if begin, ok := regionMarker(line, regionBegin); ok {
+			if name != "" {
+				return nil, fmt.Errorf("%s:%d: region %q starts inside region %q", path, i+1, begin, name)
+			}
+			name, body, start = begin, nil, i+1
+			continue
+		}
+		if end, ok := regionMarker(line, regionEnd); ok {
+			if end != name {
+				return nil, fmt.Errorf("%s:%d: unexpected end of region %q", path, i+1, end)
+			}
+			r.content[name] = strings.Join(body, "\n")
+			name = ""
+			continue
+		}
+		if name != "" {
+			body = append(body, line)
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("%s:%d: region %q is never closed", path, start, name)
+	}
+	return r, nil
+}
+
+// regionMarker parses a begin or end marker line, whatever its comment
+// prefix, and returns the region name.
+func regionMarker(line, marker string) (string, bool) {
+	i := strings.Index(line, marker)
+	if i < 0 {
+		return "", false
+	}
+	name := strings.TrimSpace(line[i+len(marker):])
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", false
+	}
+	return name, true
+}
+
+// region renders a protected region declared by the template, with the
+// content it had in the existing output. The comment prefix defaults to
+// the one of the output language.
+func (r *regions) region(ext, name string, prefix ...string) (string, error) {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return "", fmt.Errorf("invalid region name %q", name)
+	}
+	if r.current[name] {
+		return "", fmt.Errorf("region %q is declared twice", name)
+	}
+	r.current[name] = true
+	r.declared[name] = true
+
+	comment := "//"
+	if p, ok := commentPrefixes[ext]; ok {
+		comment = p
+	}
+	if len(prefix) > 0 {
+		comment = prefix[0]
+	}
+
+	var b strings.Builder
+	b.WriteString(comment + " " + regionBegin + name + "\n")
+	if content, ok := r.content[name]; ok {
+		b.WriteString(content + "\n")
+	}
+	b.WriteString(comment + " " + regionEnd + name)
+	return b.String(), nil
+}
+
+// check reports regions of the existing output with content that the
+// template no longer declares, which would otherwise be lost.
+func (r *regions) check() error {
+	var lost []string
+	for name, content := range r.content {
+		if !r.declared[name] && strings.TrimSpace(content) != "" {
+			lost = append(lost, name)
+		}
+	}
+	if len(lost) == 0 {
+		return nil
+	}
+	sort.Strings(lost)
+	if len(lost) == 1 {
+		return fmt.Errorf("%s: region %s is not declared by the template; its content would be lost", r.path, lost[0])
+	}
+	return fmt.Errorf("%s: regions %s are not declared by the template; their content would be lost", r.path, strings.Join(lost, ", "))
+}
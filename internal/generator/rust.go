@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// rustKeywords are reserved words that must be written as raw identifiers.
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true,
+	"crate": true, "dyn": true, "else": true, "enum": true, "extern": true, "false": true,
+	"fn": true, "for": true, "if": true, "impl": true, "in": true, "let": true, "loop": true,
+	"match": true, "mod": true, "move": true, "mut": true, "pub": true, "ref": true,
+	"return": true, "self": true, "static": true, "struct": true, "super": true,
+	"trait": true, "true": true, "type": true, "unsafe": true, "use": true, "where": true,
+	"while": true, "abstract": true, "become": true, "box": true, "do": true, "final": true,
+	"macro": true, "override": true, "priv": true, "try": true, "typeof": true,
+	"unsized": true, "virtual": true, "yield": true,
+}
+
+// rustImports lists where names used in generated types come from.
+var rustImports = map[string]string{
+	"HashMap":  "std::collections",
+	"DateTime": "chrono",
+	"Utc":      "chrono",
+	"Uuid":     "uuid",
+	"Decimal":  "rust_decimal",
+}
+
+// rustName returns the snake_case Rust field name for a Go field.
+func rustName(f model.Field) string {
+	name := snakeCase(f.Name)
+	if rustKeywords[name] {
+		return "r#" + name
+	}
+	return name
+}
+
+// rustType returns the Rust type of a field declared in t. Pointers to the
+// containing type are boxed, and omitempty fields become Option<T>.
+func rustType(cfg *config.Config, t model.Type, f model.Field) string {
+	ref := f.Type
+	var typ string
+	if ref.Kind == model.KindPointer && ref.Elem != nil && ref.Elem.Name == t.Name {
+		typ = "Option<Box<" + t.Name + ">>"
+	} else {
+		typ = mapTargetType(cfg, "rust", ref)
+	}
+
+	if isOptional(f) && !strings.HasPrefix(typ, "Option<") {
+		typ = "Option<" + typ + ">"
+	}
+	return typ
+}
+
+// rustField returns the serde attributes and declaration of a struct field,
+// indented for use inside a struct body.
+func rustField(cfg *config.Config, t model.Type, f model.Field) string {
+	if f.IsEmbedded {
+		// Fields promoted from an embedded struct are flattened by encoding/json
+		return fmt.Sprintf("    #[serde(flatten)]\n    pub %s: %s,", rustName(f), f.Name)
+	}
+
+	name := rustName(f)
+	typ := rustType(cfg, t, f)
+
+	var args []string
+	if jsonName := tagOrName(f, cfg.Options.TagKey); jsonName != strings.TrimPrefix(name, "r#") {
+		args = append(args, fmt.Sprintf("rename = %q", jsonName))
+	}
+	if f.Optional {
+		args = append(args, "default", `skip_serializing_if = "Option::is_none"`)
+	}
+
+	var b strings.Builder
+	if len(args) > 0 {
+		fmt.Fprintf(&b, "    #[serde(%s)]\n", strings.Join(args, ", "))
+	}
+	fmt.Fprintf(&b, "    pub %s: %s,", name, typ)
+	return b.String()
+}
+
+// rustEnumRepr returns the integer representation for an int-valued enum.
+func rustEnumRepr(cfg *config.Config, t model.Type) string {
+	if t.Underlying != nil {
+		if mapped, ok := cfg.MapTargetType("rust", t.Underlying.Name); ok && (strings.HasPrefix(mapped, "i") || strings.HasPrefix(mapped, "u")) {
+			return mapped
+		}
+	}
+	return "i64"
+}
+
+// rustImportLines returns the use declarations needed by the generated module.
+func rustImportLines(cfg *config.Config, types []model.Type) []string {
+	used := make(map[string]bool)
+	extra := map[string][]string{"serde": {"Deserialize", "Serialize"}}
+
+	for _, t := range types {
+		switch {
+		case enumKind(t) == "int":
+			extra["serde_repr"] = []string{"Deserialize_repr", "Serialize_repr"}
+		case enumKind(t) == "string":
+		case t.Kind == model.KindStruct:
+			for _, f := range t.Fields {
+				collectIdents(rustType(cfg, t, f), used)
+			}
+		case t.Underlying != nil:
+			collectIdents(mapTargetType(cfg, "rust", *t.Underlying), used)
+		}
+	}
+
+	return importLines(used, rustImports, extra, func(module string, names []string) string {
+		if len(names) == 1 {
+			return fmt.Sprintf("use %s::%s;", module, names[0])
+		}
+		return fmt.Sprintf("use %s::{%s};", module, strings.Join(names, ", "))
+	})
+}
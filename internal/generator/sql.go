@@ -0,0 +1,313 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// sqlJSONTypes holds the column type used for composite values (slices,
+// maps, nested structs), which are stored as JSON.
+var sqlJSONTypes = map[string]string{
+	"postgres": "JSONB",
+	"sqlite":   "TEXT",
+}
+
+// sqlTable is a table ready to be rendered.
+type sqlTable struct {
+	Name        string          // Table name
+	Type        string          // Go type the table was generated from
+	Doc         string          // Documentation comment
+	Columns     []sqlColumn     // Columns in field order
+	PrimaryKey  []string        // Primary key columns
+	ForeignKeys []sqlForeignKey // References to other generated tables
+	Indexes     []sqlIndex      // Secondary indexes
+}
+
+// sqlColumn is a table column.
+type sqlColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Doc     string
+}
+
+// sqlForeignKey is a column referencing the primary key of another table.
+type sqlForeignKey struct {
+	Column    string
+	Table     string
+	RefColumn string
+}
+
+// sqlIndex is a secondary (optionally unique) index.
+type sqlIndex struct {
+	Name    string
+	Table   string
+	Columns []string
+	Unique  bool
+}
+
+// Definitions returns the column and constraint definitions of the table,
+// in the order they appear in CREATE TABLE.
+func (t sqlTable) Definitions() []string {
+	var defs []string
+	for _, c := range t.Columns {
+		def := c.Name + " " + c.Type
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+	if len(t.PrimaryKey) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(t.PrimaryKey, ", ")+")")
+	}
+	for _, fk := range t.ForeignKeys {
+		defs = append(defs, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", fk.Column, fk.Table, fk.RefColumn))
+	}
+	return defs
+}
+
+// sqlColumnOptions are the per-column options from the sql tag
+// (sql:"pk,unique,fk=User") and field directives (//gogen:fk User).
+type sqlColumnOptions struct {
+	PrimaryKey bool
+	Unique     bool
+	Index      bool
+	References string // Referenced type, optionally with a column ("User.id")
+}
+
+// sqlColumnOptionsOf parses the column options of a field.
+func sqlColumnOptionsOf(f model.Field) sqlColumnOptions {
+	var opts sqlColumnOptions
+	for _, part := range strings.Split(f.Tag.Values["sql"], ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "pk", "primarykey":
+			opts.PrimaryKey = true
+		case "unique":
+			opts.Unique = true
+		case "index":
+			opts.Index = true
+		case "fk", "references":
+			opts.References = value
+		}
+	}
+	if f.Directives.Has("pk") {
+		opts.PrimaryKey = true
+	}
+	if f.Directives.Has("unique") {
+		opts.Unique = true
+	}
+	if f.Directives.Has("index") {
+		opts.Index = true
+	}
+	if ref := f.Directives["fk"]; ref != "" {
+		opts.References = ref
+	}
+	return opts
+}
+
+// sqlTableName returns the table name of a type: the //gogen:table
+// directive, or the pluralized snake_case type name.
+func sqlTableName(t model.Type) string {
+	if name := t.Directives["table"]; name != "" {
+		return name
+	}
+	return pluralize(snakeCase(t.Name))
+}
+
+// pluralize returns the English plural of a lower-case word.
+func pluralize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+// hasDBColumns reports whether a struct has at least one db-tagged field.
+func hasDBColumns(t model.Type) bool {
+	for _, f := range t.Fields {
+		if name := tagName(f, "db"); name != "" && name != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlTables returns the tables for structs with db tags, ordered so that
+// referenced tables are created before the tables referencing them.
+func sqlTables(cfg *config.Config, dialect string, types []model.Type) ([]sqlTable, error) {
+	named := make(map[string]model.Type, len(types))
+	tableNames := make(map[string]string)
+	for _, t := range types {
+		named[t.Name] = t
+		if t.Kind == model.KindStruct && hasDBColumns(t) {
+			tableNames[t.Name] = sqlTableName(t)
+		}
+	}
+
+	var tables []sqlTable
+	for _, t := range types {
+		if _, ok := tableNames[t.Name]; !ok {
+			continue
+		}
+		table, err := sqlTableOf(cfg, dialect, t, named, tableNames)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return sortTables(tables), nil
+}
+
+// sqlTableOf builds the table for a struct.
+func sqlTableOf(cfg *config.Config, dialect string, t model.Type, named map[string]model.Type, tableNames map[string]string) (sqlTable, error) {
+	table := sqlTable{Name: tableNames[t.Name], Type: t.Name, Doc: t.Doc}
+
+	var references []model.Field
+	for _, f := range t.Fields {
+		name := tagName(f, "db")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		opts := sqlColumnOptionsOf(f)
+		table.Columns = append(table.Columns, sqlColumn{
+			Name:    name,
+			Type:    sqlColumnType(cfg, dialect, f, named),
+			NotNull: f.Type.Kind != model.KindPointer || hasValidateRule(f, "required") || opts.PrimaryKey,
+			Doc:     f.Doc,
+		})
+		if opts.PrimaryKey {
+			table.PrimaryKey = append(table.PrimaryKey, name)
+		}
+		if opts.Unique || opts.Index {
+			suffix := "idx"
+			if opts.Unique {
+				suffix = "key"
+			}
+			table.Indexes = append(table.Indexes, sqlIndex{
+				Name:    table.Name + "_" + name + "_" + suffix,
+				Table:   table.Name,
+				Columns: []string{name},
+				Unique:  opts.Unique,
+			})
+		}
+		if opts.References != "" {
+			references = append(references, f)
+		}
+	}
+
+	// A composite primary key can be declared on the type
+	if pk := t.Directives["pk"]; pk != "" && len(table.PrimaryKey) == 0 {
+		for _, column := range strings.Split(pk, ",") {
+			table.PrimaryKey = append(table.PrimaryKey, strings.TrimSpace(column))
+		}
+	}
+	if len(table.PrimaryKey) == 0 {
+		for _, c := range table.Columns {
+			if c.Name == "id" {
+				table.PrimaryKey = []string{"id"}
+			}
+		}
+	}
+
+	for _, f := range references {
+		refType, refColumn, _ := strings.Cut(sqlColumnOptionsOf(f).References, ".")
+		refTable, ok := tableNames[refType]
+		if !ok {
+			return sqlTable{}, fmt.Errorf("field %s.%s references %s, which is not a generated table", t.Name, f.Name, refType)
+		}
+		if refColumn == "" {
+			refColumn = sqlPrimaryKeyColumn(named[refType])
+		}
+		table.ForeignKeys = append(table.ForeignKeys, sqlForeignKey{
+			Column:    tagName(f, "db"),
+			Table:     refTable,
+			RefColumn: refColumn,
+		})
+	}
+	return table, nil
+}
+
+// sqlPrimaryKeyColumn returns the single primary key column of a struct,
+// which foreign keys refer to by default.
+func sqlPrimaryKeyColumn(t model.Type) string {
+	for _, f := range t.Fields {
+		if sqlColumnOptionsOf(f).PrimaryKey {
+			return tagName(f, "db")
+		}
+	}
+	return "id"
+}
+
+// sqlColumnType returns the column type of a field. Strings with a max=
+// validation become VARCHAR(n).
+func sqlColumnType(cfg *config.Config, dialect string, f model.Field, named map[string]model.Type) string {
+	ref := f.Type
+	if ref.Kind == model.KindPointer && ref.Elem != nil {
+		ref = *ref.Elem
+	}
+	typ := sqlType(cfg, dialect, ref, named)
+	if typ == "TEXT" {
+		if n, err := strconv.Atoi(getValidateValue(f, "max")); err == nil && n > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", n)
+		}
+	}
+	return typ
+}
+
+// sqlType maps a Go type to a column type. Named types resolve to their
+// underlying type, and composite values are stored as JSON.
+func sqlType(cfg *config.Config, dialect string, ref model.TypeRef, named map[string]model.Type) string {
+	if mapped, ok := lookupTargetType(cfg, dialect, ref); ok {
+		return mapped
+	}
+	if ref.Kind == model.KindBasic {
+		if typ, ok := named[ref.Name]; ok && typ.Kind != model.KindStruct && typ.Underlying != nil {
+			return sqlType(cfg, dialect, *typ.Underlying, named)
+		}
+	}
+	if ref.Kind == model.KindPointer && ref.Elem != nil {
+		return sqlType(cfg, dialect, *ref.Elem, named)
+	}
+	return sqlJSONTypes[dialect]
+}
+
+// sortTables orders tables so that every table comes after the tables it
+// references, keeping the declaration order otherwise. Reference cycles
+// cannot be ordered and keep their declaration order.
+func sortTables(tables []sqlTable) []sqlTable {
+	created := make(map[string]bool)
+	sorted := make([]sqlTable, 0, len(tables))
+	remaining := tables
+	for len(remaining) > 0 {
+		var next []sqlTable
+		for _, t := range remaining {
+			ready := true
+			for _, fk := range t.ForeignKeys {
+				if fk.Table != t.Name && !created[fk.Table] {
+					ready = false
+				}
+			}
+			if ready {
+				created[t.Name] = true
+				sorted = append(sorted, t)
+			} else {
+				next = append(next, t)
+			}
+		}
+		if len(next) == len(remaining) {
+			return append(sorted, next...)
+		}
+		remaining = next
+	}
+	return sorted
+}
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// swiftKeywords are reserved words that must be escaped with backticks.
+var swiftKeywords = map[string]bool{
+	"associatedtype": true, "class": true, "deinit": true, "enum": true, "extension": true,
+	"fileprivate": true, "func": true, "import": true, "init": true, "inout": true,
+	"internal": true, "let": true, "open": true, "operator": true, "private": true,
+	"protocol": true, "public": true, "rethrows": true, "static": true, "struct": true,
+	"subscript": true, "typealias": true, "var": true, "break": true, "case": true,
+	"continue": true, "default": true, "defer": true, "do": true, "else": true,
+	"fallthrough": true, "for": true, "guard": true, "if": true, "in": true, "repeat": true,
+	"return": true, "switch": true, "where": true, "while": true, "as": true, "catch": true,
+	"false": true, "is": true, "nil": true, "self": true, "super": true, "throw": true,
+	"throws": true, "true": true, "try": true,
+}
+
+// swiftEscape escapes Swift keywords used as identifiers.
+func swiftEscape(name string) string {
+	if swiftKeywords[name] {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+// swiftName returns the camelCase Swift property name for a Go field.
+func swiftName(f model.Field) string {
+	return swiftEscape(camelCase(f.Name))
+}
+
+// swiftType returns the Swift type of a field, optional for pointers and
+// omitempty fields.
+func swiftType(cfg *config.Config, f model.Field) string {
+	typ := mapTargetType(cfg, "swift", f.Type)
+	if isOptional(f) && !strings.HasSuffix(typ, "?") {
+		typ += "?"
+	}
+	return typ
+}
+
+// swiftCodingKey returns the CodingKeys case for a field, with an explicit
+// raw value when the JSON name differs from the property name.
+func swiftCodingKey(cfg *config.Config, f model.Field) string {
+	name := swiftName(f)
+	if jsonName := tagOrName(f, cfg.Options.TagKey); jsonName != strings.Trim(name, "`") {
+		return name + ` = "` + jsonName + `"`
+	}
+	return name
+}
+
+// swiftEnumCase returns the Swift enum case name for a constant.
+func swiftEnumCase(t model.Type, c model.Constant) string {
+	return swiftEscape(camelCase(constName(t, c)))
+}
+
+// swiftIsRecursive reports whether a struct refers to itself through a
+// pointer, which Swift value types cannot represent (such types are
+// generated as final classes instead).
+func swiftIsRecursive(t model.Type) bool {
+	for _, f := range t.Fields {
+		if f.Type.Kind == model.KindPointer && f.Type.Elem != nil && f.Type.Elem.Name == t.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// swiftUsesAnyCodable reports whether any generated type needs AnyCodable.
+func swiftUsesAnyCodable(cfg *config.Config, types []model.Type) bool {
+	used := make(map[string]bool)
+	for _, t := range types {
+		for _, f := range t.Fields {
+			collectIdents(swiftType(cfg, f), used)
+		}
+		if t.Underlying != nil {
+			collectIdents(mapTargetType(cfg, "swift", *t.Underlying), used)
+		}
+	}
+	return used["AnyCodable"]
+}
@@ -0,0 +1,223 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gogen/internal/config"
+	"gogen/internal/model"
+)
+
+// targetLang describes how a target language spells composite types.
+type targetLang struct {
+	List     string // Format for slices, given the element type (e.g., "List[%s]")
+	Tuple    string // Format for fixed-length arrays, given the comma-separated element types
+	Array    string // Format for fixed-length arrays, given the element type and length (preferred over Tuple)
+	Map      string // Format for maps, given the key and value types (e.g., "Dict[%s, %s]"), or a single type for all maps
+	Nullable string // Format for pointers, given the element type (e.g., "Optional[%s]")
+	Unknown  string // Type for interfaces, anonymous structs and unsupported kinds
+}
+
+// targetLangs holds the built-in target languages, keyed by target name.
+var targetLangs = map[string]targetLang{
+	"python": {
+		List:     "List[%s]",
+		Tuple:    "Tuple[%s]",
+		Map:      "Dict[%s, %s]",
+		Nullable: "Optional[%s]",
+		Unknown:  "Any",
+	},
+	"rust": {
+		List:     "Vec<%s>",
+		Array:    "[%s; %d]",
+		Map:      "HashMap<%s, %s>",
+		Nullable: "Option<%s>",
+		Unknown:  "serde_json::Value",
+	},
+	"kotlin": {
+		List:     "List<%s>",
+		Map:      "Map<%s, %s>",
+		Nullable: "%s?",
+		Unknown:  "JsonElement",
+	},
+	"swift": {
+		List:     "[%s]",
+		Map:      "[%s: %s]",
+		Nullable: "%s?",
+		Unknown:  "AnyCodable",
+	},
+	"proto": {
+		List:     "repeated %s",
+		Map:      "map<%s, %s>",
+		Nullable: "%s",
+		Unknown:  "google.protobuf.Value",
+	},
+	"graphql": {
+		List:     "[%s]",
+		Map:      "JSON",
+		Nullable: "%s",
+		Unknown:  "JSON",
+	},
+}
+
+// mapTargetType maps a Go type to a type of the given target language,
+// using the target's mapping table for named types.
+func mapTargetType(cfg *config.Config, target string, t model.TypeRef) string {
+	lang, ok := targetLangs[target]
+	if !ok {
+		return mapType(cfg, t)
+	}
+
+	if mapped, ok := lookupTargetType(cfg, target, t); ok {
+		return mapped
+	}
+
+	switch t.Kind {
+	case model.KindArray:
+		if t.Elem != nil && t.Len > 0 && lang.Array != "" {
+			return fmt.Sprintf(lang.Array, mapTargetType(cfg, target, *t.Elem), t.Len)
+		}
+		if t.Elem != nil && isTuple(t) && lang.Tuple != "" {
+			elem := mapTargetType(cfg, target, *t.Elem)
+			elems := make([]string, t.Len)
+			for i := range elems {
+				elems[i] = elem
+			}
+			return fmt.Sprintf(lang.Tuple, strings.Join(elems, ", "))
+		}
+		if t.Elem != nil {
+			return fmt.Sprintf(lang.List, mapTargetType(cfg, target, *t.Elem))
+		}
+	case model.KindSlice:
+		if t.Elem != nil {
+			return fmt.Sprintf(lang.List, mapTargetType(cfg, target, *t.Elem))
+		}
+	case model.KindMap:
+		if !strings.Contains(lang.Map, "%") {
+			// The target has a single type for all maps
+			return lang.Map
+		}
+		if t.Key != nil && t.Value != nil {
+			return fmt.Sprintf(lang.Map, mapTargetType(cfg, target, *t.Key), mapTargetType(cfg, target, *t.Value))
+		}
+	case model.KindPointer:
+		if t.Elem != nil {
+			return fmt.Sprintf(lang.Nullable, mapTargetType(cfg, target, *t.Elem))
+		}
+	case model.KindBasic:
+		if t.Name != "unknown" {
+			// Reference to another generated type
+			return t.Name
+		}
+	}
+
+	return lang.Unknown
+}
+
+// lookupTargetType looks a type up in the target's mapping table, from the
+// most to the least specific name.
+func lookupTargetType(cfg *config.Config, target string, t model.TypeRef) (string, bool) {
+	candidates := []string{t.QualifiedName(), t.Raw, t.FullName(), t.Name}
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if mapped, ok := cfg.MapTargetType(target, name); ok {
+			return mapped, true
+		}
+	}
+	return "", false
+}
+
+// namedTypes indexes types by name.
+func namedTypes(all []model.Type) map[string]model.Type {
+	named := make(map[string]model.Type, len(all))
+	for _, t := range all {
+		named[t.Name] = t
+	}
+	return named
+}
+
+// identifierRe matches identifiers in generated type annotations.
+var identifierRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// collectIdents records the identifiers used in a type annotation.
+func collectIdents(annotation string, used map[string]bool) {
+	for _, ident := range identifierRe.FindAllString(annotation, -1) {
+		used[ident] = true
+	}
+}
+
+// importLines groups used identifiers by the module that provides them and
+// formats one import statement per module, in sorted order.
+func importLines(used map[string]bool, modules map[string]string, extra map[string][]string, format func(module string, names []string) string) []string {
+	byModule := make(map[string][]string)
+	for module, names := range extra {
+		byModule[module] = append(byModule[module], names...)
+	}
+	for name := range used {
+		if module, ok := modules[name]; ok {
+			byModule[module] = append(byModule[module], name)
+		}
+	}
+
+	sorted := make([]string, 0, len(byModule))
+	for module := range byModule {
+		sorted = append(sorted, module)
+	}
+	sort.Strings(sorted)
+
+	lines := make([]string, 0, len(sorted))
+	for _, module := range sorted {
+		names := byModule[module]
+		sort.Strings(names)
+		lines = append(lines, format(module, names))
+	}
+	return lines
+}
+
+// enumKind returns "string", "int" or "float" depending on the values of a
+// type's constants, or "" if the type has no evaluable constants.
+func enumKind(t model.Type) string {
+	if len(t.Constants) == 0 {
+		return ""
+	}
+	kind := "int"
+	for _, c := range t.Constants {
+		switch {
+		case strings.HasPrefix(c.Value, `"`):
+			kind = "string"
+		case strings.ContainsAny(c.Value, ".eE") && kind == "int":
+			kind = "float"
+		case !isNumberLiteral(c.Value):
+			return ""
+		}
+	}
+	return kind
+}
+
+// isNumberLiteral reports whether s is a (possibly negative) number literal.
+func isNumberLiteral(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' && r != 'e' && r != 'E' && r != '-' && r != '+' {
+			return false
+		}
+	}
+	return true
+}
+
+// constName returns a constant's name with the type name prefix removed
+// (e.g., "RoleAdmin" of type "Role" -> "Admin").
+func constName(t model.Type, c model.Constant) string {
+	name := strings.TrimPrefix(c.Name, t.Name)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return c.Name
+	}
+	return name
+}
@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"gogen/internal/diag"
+	"gogen/internal/model"
+)
+
+// Variants derived from a struct for API payloads.
+const (
+	VariantCreate = "create" // CreateXInput: the fields clients set, without readonly ones
+	VariantUpdate = "update" // UpdateXInput: the create fields, all optional
+)
+
+// structVariants returns the variants to derive from a type: those of its
+// //gogen:variants directive ("create", "update", or "none"; all of them
+// if empty), or else options.variants.
+func (g *Generator) structVariants(t model.Type) ([]string, error) {
+	variants := g.config.Options.Variants
+	args, ok := t.Directives["variants"]
+	if ok {
+		variants = strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(variants) == 0 {
+			variants = []string{VariantCreate, VariantUpdate}
+		}
+	}
+
+	var result []string
+	for _, v := range variants {
+		switch v {
+		case VariantCreate, VariantUpdate:
+			result = append(result, v)
+		case "none":
+			if ok {
+				return nil, nil
+			}
+			fallthrough
+		default:
+			if ok {
+				return nil, diag.Errorf(t.Pos, diag.CodeDirective, "unknown variant %q for %s (want create, update or none)", v, t.Name)
+			}
+			return nil, fmt.Errorf("options.variants: unknown variant %q (want create or update)", v)
+		}
+	}
+	return result, nil
+}
+
+// expandVariants derives the input variants of structs after each of
+// them, and drops write-only fields from the structs themselves, which
+// become the response shapes (whether or not they have variants). Readonly
+// fields, set by the server, are left out of inputs. Fields keep referring
+// to the declared types. SQL and Go outputs describe stored and Go types,
+// and keep the declared structs.
+func (g *Generator) expandVariants(types []model.Type) ([]model.Type, error) {
+	if g.keepsDeclaredShapes() {
+		return types, nil
+	}
+
+	result := make([]model.Type, 0, len(types))
+	for _, t := range types {
+		if t.Kind != model.KindStruct {
+			result = append(result, t)
+			continue
+		}
+		variants, err := g.structVariants(t)
+		if err != nil {
+			return nil, err
+		}
+
+		response := t
+		response.Fields = nil
+		for _, f := range t.Fields {
+			if !f.WriteOnly {
+				response.Fields = append(response.Fields, f)
+			}
+		}
+		result = append(result, response)
+		for _, v := range variants {
+			result = append(result, variantOf(t, v))
+		}
+	}
+	return result, nil
+}
+
+// variantOf derives an input variant from a struct.
+func variantOf(t model.Type, variant string) model.Type {
+	name := "Create" + t.Name + "Input"
+	doc := fmt.Sprintf("%s is the input to create a %s.", name, t.Name)
+	if variant == VariantUpdate {
+		name = "Update" + t.Name + "Input"
+		doc = fmt.Sprintf("%s is the input to update a %s. All fields are optional.", name, t.Name)
+	}
+
+	v := model.Type{
+		Name:       name,
+		Kind:       model.KindStruct,
+		Doc:        doc,
+		IsExported: t.IsExported,
+		Pos:        t.Pos,
+		Variant:    variant,
+	}
+	for _, f := range t.Fields {
+		if f.Readonly {
+			continue
+		}
+		f.WriteOnly = false
+		if variant == VariantUpdate {
+			f.Optional = true
+		}
+		v.Fields = append(v.Fields, f)
+	}
+	return v
+}
@@ -1,6 +1,8 @@
 // Package model defines the intermediate representation for parsed Go types.
 package model
 
+import "fmt"
+
 // TypeKind represents the category of a Go type.
 type TypeKind string
 
@@ -14,6 +16,8 @@ const (
 	KindMap       TypeKind = "map"
 	KindPointer   TypeKind = "pointer"
 	KindInterface TypeKind = "interface"
+	KindChan      TypeKind = "chan"
+	KindFunc      TypeKind = "func"
 )
 
 // File represents a parsed Go source file.
@@ -30,24 +34,85 @@ type Import struct {
 	Path  string // Import path
 }
 
+// Position represents a location in a Go source file.
+type Position struct {
+	File   string // File path
+	Line   int    // Line number (1-based)
+	Column int    // Column number (1-based)
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "file:line:column" (or "file:line" if
+// the column is unknown).
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	if p.Column == 0 {
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
 // Type represents a Go type definition.
 type Type struct {
-	Name       string   // Type name (e.g., "User")
-	Kind       TypeKind // Type category
-	Doc        string   // Documentation comment
-	Fields     []Field  // Fields (for structs)
-	Underlying *TypeRef // Underlying type (for aliases/named types)
-	IsExported bool     // Whether the type is exported
+	Name       string     // Type name (e.g., "User")
+	Kind       TypeKind   // Type category
+	Doc        string     // Documentation comment
+	Fields     []Field    // Fields (for structs)
+	Underlying *TypeRef   // Underlying type (for aliases/named types)
+	IsExported bool       // Whether the type is exported
+	Pos        Position   // Source position of the type name
+	Constants  []Constant // Typed constants declared for this type (enum values)
+	Directives Directives // gogen directives from the doc comment
+	Marshaler  string     // Interface changing how the type serializes (JSONMarshaler, ...), if any
+	Variant    string     // Input variant derived by the generator ("create", "update"), or ""
+}
+
+// Interfaces that make a type serialize differently from its shape.
+const (
+	JSONMarshaler = "json.Marshaler"
+	TextMarshaler = "encoding.TextMarshaler"
+	Stringer      = "fmt.Stringer"
+)
+
+// Directives holds "//gogen:name args" comment directives (name -> args).
+type Directives map[string]string
+
+// Has reports whether the directive is present.
+func (d Directives) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// Constant represents a typed constant declaration (e.g., RoleAdmin Role = "admin").
+type Constant struct {
+	Name  string   // Constant name (e.g., "RoleAdmin")
+	Value string   // Go literal value (e.g., `"admin"` or "2"), or the expression if it cannot be evaluated
+	Doc   string   // Documentation comment
+	Pos   Position // Source position of the constant name
 }
 
 // Field represents a struct field.
 type Field struct {
-	Name       string    // Field name (empty for embedded)
-	Type       TypeRef   // Field type reference
-	Tag        StructTag // Struct tag
-	Doc        string    // Documentation comment
-	IsExported bool      // Whether the field is exported
-	IsEmbedded bool      // Whether this is an embedded field
+	Name       string     // Field name (empty for embedded)
+	Type       TypeRef    // Field type reference
+	Tag        StructTag  // Struct tag
+	Doc        string     // Documentation comment
+	Comment    string     // Trailing line comment
+	IsExported bool       // Whether the field is exported
+	IsEmbedded bool       // Whether this is an embedded field
+	Via        string     // Embedded struct the field was promoted from (set when flattening)
+	Pos        Position   // Source position of the field
+	Directives Directives // gogen directives from the doc and trailing comments
+	Nullable   bool       // Whether the value may be null (pointers, //gogen:nullable)
+	Optional   bool       // Whether the field may be absent (omitempty, omitzero, //gogen:optional)
+	Readonly   bool       // Whether clients may not set the field (//gogen:readonly)
+	WriteOnly  bool       // Whether the field is never returned to clients (//gogen:writeonly)
 }
 
 // TypeRef represents a reference to a type.
@@ -55,16 +120,49 @@ type TypeRef struct {
 	Kind    TypeKind // Type category
 	Name    string   // Type name (for named/basic types)
 	Package string   // Package name (for imported types, e.g., "time" for time.Time)
-	Elem    *TypeRef // Element type (for slice, array, pointer)
+	PkgPath string   // Resolved import path (e.g., "github.com/google/uuid")
+	Elem    *TypeRef // Element type (for slice, array, pointer, chan)
 	Key     *TypeRef // Key type (for maps)
 	Value   *TypeRef // Value type (for maps)
+	Len     int      // Array length (for arrays with an integer literal length, 0 otherwise)
+	Fields  []Field  // Inline fields (for anonymous structs)
 	Raw     string   // Raw Go type string representation
 }
 
 // StructTag represents parsed struct tags.
 type StructTag struct {
-	Raw    string            // Raw tag string
-	Values map[string]string // Parsed tag values (key -> value)
+	Raw     string              // Raw tag string
+	Keys    []string            // Tag keys in declaration order
+	Values  map[string]string   // Parsed tag values (key -> value)
+	Options map[string]TagValue // Structured tag values (key -> name + options)
+}
+
+// TagValue represents a tag value split into its name and options
+// (e.g., `json:"name,omitempty"` -> Name "name", Options ["omitempty"]).
+type TagValue struct {
+	Name    string   // First comma-separated element
+	Options []string // Remaining comma-separated elements
+}
+
+// HasOption reports whether the tag value contains the given option.
+func (v TagValue) HasOption(opt string) bool {
+	for _, o := range v.Options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the structured value for a tag key.
+func (t StructTag) Get(key string) (TagValue, bool) {
+	v, ok := t.Options[key]
+	return v, ok
+}
+
+// HasOption reports whether the tag for key contains the given option.
+func (t StructTag) HasOption(key, opt string) bool {
+	return t.Options[key].HasOption(opt)
 }
 
 // FullName returns the full qualified name of a TypeRef (e.g., "time.Time").
@@ -74,3 +172,13 @@ func (t *TypeRef) FullName() string {
 	}
 	return t.Name
 }
+
+// QualifiedName returns the import-path qualified name of a TypeRef
+// (e.g., "github.com/google/uuid.UUID"). It falls back to FullName when
+// the import path is unknown.
+func (t *TypeRef) QualifiedName() string {
+	if t.PkgPath != "" {
+		return t.PkgPath + "." + t.Name
+	}
+	return t.FullName()
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"sync"
+
+	"gogen/internal/model"
+)
+
+// Cache shares parse results between the targets of a run, so that an input
+// used by several targets is parsed once. It is safe for concurrent use:
+// callers asking for an input being parsed wait for that parse instead of
+// starting their own. Parsed files must be treated as read-only.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is the result of one parse, available once done is closed.
+type cacheEntry struct {
+	done chan struct{}
+	file *model.File
+	err  error
+}
+
+// NewCache creates an empty parse cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*cacheEntry)}
+}
+
+// Parse returns the result of parse for key, calling parse only the first
+// time key is seen. Errors are cached too, so an input that fails to parse
+// reports the same error to every target.
+func (c *Cache) Parse(key string, parse func() (*model.File, error)) (*model.File, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-e.done
+		return e.file, e.err
+	}
+	e.file, e.err = parse()
+	close(e.done)
+	return e.file, e.err
+}
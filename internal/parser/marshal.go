@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"gogen/internal/diag"
+	"gogen/internal/model"
+)
+
+// Interfaces that change how a type serializes, by priority.
+var marshalers = []struct {
+	name  string
+	iface *types.Interface
+}{
+	{model.JSONMarshaler, methodInterface("MarshalJSON", types.NewSlice(types.Typ[types.Byte]), types.Universe.Lookup("error").Type())},
+	{model.TextMarshaler, methodInterface("MarshalText", types.NewSlice(types.Typ[types.Byte]), types.Universe.Lookup("error").Type())},
+	{model.Stringer, methodInterface("String", types.Typ[types.String])},
+}
+
+// methodInterface returns an interface with a single method taking no
+// arguments, so that marshalers can be detected without loading the
+// packages declaring them.
+func methodInterface(name string, results ...types.Type) *types.Interface {
+	vars := make([]*types.Var, len(results))
+	for i, r := range results {
+		vars[i] = types.NewParam(token.NoPos, nil, "", r)
+	}
+	sig := types.NewSignatureType(nil, nil, nil, nil, types.NewTuple(vars...), false)
+	return types.NewInterfaceType([]*types.Func{types.NewFunc(token.NoPos, nil, name, sig)}, nil).Complete()
+}
+
+// emptyImporter imports every package as an empty package. Imported
+// packages are not loaded: only the method sets of the package's own types
+// matter, and their marshaling methods use predeclared types only.
+type emptyImporter struct{}
+
+func (emptyImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, PackageName(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// findMarshalers type-checks the files of a package and returns the
+// package-scope types implementing json.Marshaler, encoding.TextMarshaler
+// or fmt.Stringer, with value or pointer receivers (type name -> interface
+// name). Methods declared in files that are not parsed, or promoted from
+// imported types, are not seen.
+func (p *Parser) findMarshalers(pkg string, files []*ast.File) map[string]string {
+	conf := types.Config{
+		Importer: emptyImporter{},
+		Error:    func(error) {}, // References to imported packages do not resolve
+	}
+	checked, _ := conf.Check(pkg, p.fset, files, nil)
+	if checked == nil {
+		return nil
+	}
+
+	result := make(map[string]string)
+	scope := checked.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || types.IsInterface(tn.Type()) {
+			continue
+		}
+		for _, m := range marshalers {
+			if hasMethod(checked, tn.Type(), m.iface) {
+				result[name] = m.name
+				break
+			}
+		}
+	}
+	return result
+}
+
+// hasMethod reports whether t or *t has the single method of iface. The
+// method must be found, declared in the package or promoted from a valid
+// embedded type: types.Implements assumes types involving an unresolved
+// import (e.g., a struct embedding sql.NullString) implement everything.
+func hasMethod(pkg *types.Package, t types.Type, iface *types.Interface) bool {
+	want := iface.Method(0)
+	obj, _, _ := types.LookupFieldOrMethod(t, true, pkg, want.Name())
+	fn, ok := obj.(*types.Func)
+	return ok && types.Identical(fn.Type(), want.Type())
+}
+
+// attachMarshalers records the marshaling interfaces of the types, and
+// warns about structs embedding imported types: the methods of imported
+// packages are not loaded, so a marshaler promoted from them is not seen.
+// The //gogen:marshal directive of a struct silences the warning.
+func (p *Parser) attachMarshalers(result *model.File, marshalers map[string]string) {
+	for i := range result.Types {
+		t := &result.Types[i]
+		t.Marshaler = marshalers[t.Name]
+		if t.Kind != model.KindStruct || t.Marshaler != "" || t.Directives.Has("marshal") {
+			continue
+		}
+		for _, f := range t.Fields {
+			ref := f.Type
+			if ref.Kind == model.KindPointer && ref.Elem != nil {
+				ref = *ref.Elem
+			}
+			if f.IsEmbedded && ref.PkgPath != "" {
+				p.diags.Warnf(f.Pos, diag.CodeMarshaler, "%s embeds %s, whose marshaling methods are not loaded: add //gogen:marshal to %s if it serializes differently", t.Name, ref.Raw, t.Name)
+			}
+		}
+	}
+}
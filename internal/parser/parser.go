@@ -2,31 +2,104 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/constant"
 	"go/parser"
+	"go/printer"
 	"go/token"
-	"reflect"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"gogen/internal/diag"
 	"gogen/internal/model"
 )
 
-// Parser parses Go source files and extracts type definitions.
+// Parser parses Go source files and extracts type definitions. A Parser
+// is safe for concurrent use: state of the file being parsed is kept per
+// call.
 type Parser struct {
-	fset *token.FileSet
+	fset       *token.FileSet    // Shared by all calls; token.FileSet is safe for concurrent use
+	imports    map[string]string // Local package name -> import path for the file being parsed
+	localTypes bool              // Whether to collect types declared inside function bodies
+	diags      *diag.Collector   // Receives warnings about unsupported constructs (may be nil)
+	tagKey     string            // Tag key whose "-" name skips fields (default "json")
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// WithLocalTypes makes the parser also collect types declared inside
+// function bodies. Their names are prefixed with the enclosing function
+// (e.g., "handleUser_tmp", or "Server_handle_tmp" for methods) so they
+// cannot collide with package-scope types.
+func WithLocalTypes() Option {
+	return func(p *Parser) {
+		p.localTypes = true
+	}
+}
+
+// WithDiagnostics makes the parser report warnings about constructs it
+// cannot represent (e.g., chan or func fields) to c.
+func WithDiagnostics(c *diag.Collector) Option {
+	return func(p *Parser) {
+		p.diags = c
+	}
+}
+
+// WithTagKey sets the tag key naming fields in generated code (e.g.,
+// "yaml"), whose "-" name skips a field: skipped fields are not checked.
+func WithTagKey(key string) Option {
+	return func(p *Parser) {
+		if key != "" {
+			p.tagKey = key
+		}
+	}
 }
 
 // New creates a new Parser.
-func New() *Parser {
+func New(opts ...Option) *Parser {
+	p := &Parser{
+		fset:   token.NewFileSet(),
+		tagKey: "json",
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// call returns the parser used by a single parse call, with its own
+// per-file state.
+func (p *Parser) call() *Parser {
 	return &Parser{
-		fset: token.NewFileSet(),
+		fset:       p.fset,
+		localTypes: p.localTypes,
+		diags:      p.diags,
+		tagKey:     p.tagKey,
 	}
 }
 
 // ParseFile parses a single Go source file and returns its type definitions.
 func (p *Parser) ParseFile(path string) (*model.File, error) {
-	file, err := parser.ParseFile(p.fset, path, nil, parser.ParseComments)
+	return p.ParseSource(path, nil)
+}
+
+// ParseSource parses Go source read from memory (e.g., from stdin) rather
+// than from a file. The path is used in positions and as the file path of
+// the result; if src is nil, the source is read from path.
+func (p *Parser) ParseSource(path string, src []byte) (*model.File, error) {
+	p = p.call()
+
+	var source any
+	if src != nil {
+		source = src
+	}
+	file, err := parser.ParseFile(p.fset, path, source, parser.ParseComments)
 	if err != nil {
 		return nil, fmt.Errorf("parsing %s: %w", path, err)
 	}
@@ -34,31 +107,365 @@ func (p *Parser) ParseFile(path string) (*model.File, error) {
 	result := &model.File{
 		Package: file.Name.Name,
 		Path:    path,
+		Imports: p.extractImports(file),
 	}
+	constants := make(map[string][]model.Constant)
+	p.extractFile(result, file, constants)
+	attachConstants(result, constants)
+	p.attachMarshalers(result, p.findMarshalers(result.Package, []*ast.File{file}))
+	return result, nil
+}
+
+// ParseDir parses the Go package in a directory, as go build would see it
+// for the current platform, and returns the type definitions of all its
+// files. Test files and generated files (with a "Code generated ... DO NOT
+// EDIT." header) are skipped, so that outputs written next to the input are
+// not read back. If pkg is not empty, only files of that package are parsed;
+// otherwise the directory must hold a single package.
+func (p *Parser) ParseDir(dir, pkg string) (*model.File, error) {
+	p = p.call()
 
-	// Extract imports
-	result.Imports = p.extractImports(file)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading package directory: %w", err)
+	}
 
-	// Extract types using ast.Inspect
-	ast.Inspect(file, func(n ast.Node) bool {
-		genDecl, ok := n.(*ast.GenDecl)
-		if !ok || genDecl.Tok != token.TYPE {
-			return true
+	result := &model.File{
+		Package: pkg,
+		Path:    dir,
+	}
+	constants := make(map[string][]model.Constant)
+	seen := make(map[model.Import]bool)
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
 		}
 
-		for _, spec := range genDecl.Specs {
-			typeSpec, ok := spec.(*ast.TypeSpec)
-			if !ok {
+		path := filepath.Join(dir, name)
+		file, err := parser.ParseFile(p.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if ast.IsGenerated(file) {
+			continue
+		}
+		if result.Package == "" {
+			result.Package = file.Name.Name
+		}
+		if file.Name.Name != result.Package {
+			if pkg != "" {
 				continue
 			}
+			return nil, fmt.Errorf("parsing %s: found packages %s and %s", dir, result.Package, file.Name.Name)
+		}
 
-			t := p.extractType(typeSpec, genDecl.Doc)
-			result.Types = append(result.Types, t)
+		files = append(files, file)
+		p.extractFile(result, file, constants)
+		for _, imp := range p.extractImports(file) {
+			if !seen[imp] {
+				seen[imp] = true
+				result.Imports = append(result.Imports, imp)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("parsing %s: no Go files", dir)
+	}
+
+	attachConstants(result, constants)
+	p.attachMarshalers(result, p.findMarshalers(result.Package, files))
+	return result, nil
+}
+
+// extractFile adds the types and typed constants of a parsed file.
+func (p *Parser) extractFile(result *model.File, file *ast.File, constants map[string][]model.Constant) {
+	// Type references are resolved with the imports of their own file
+	p.imports = importNames(p.extractImports(file))
+
+	// Extract package-scope types and typed constants
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			result.Types = append(result.Types, p.extractTypeDecl(d)...)
+			if d.Tok == token.CONST {
+				p.extractConstants(d, constants)
+			}
+		case *ast.FuncDecl:
+			if p.localTypes && d.Body != nil {
+				result.Types = append(result.Types, p.extractLocalTypes(d)...)
+			}
+		}
+	}
+}
+
+// attachConstants attaches typed constants to their types.
+func attachConstants(result *model.File, constants map[string][]model.Constant) {
+	for i := range result.Types {
+		result.Types[i].Constants = constants[result.Types[i].Name]
+	}
+}
+
+// extractConstants collects typed constants from a const declaration,
+// grouped by type name. Values are evaluated where possible, including
+// iota and implicitly repeated expressions.
+func (p *Parser) extractConstants(genDecl *ast.GenDecl, constants map[string][]model.Constant) {
+	var (
+		typeName string
+		exprs    []ast.Expr
+	)
+	values := make(map[string]constant.Value)
+
+	for iota, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		// A spec without values repeats the previous type and expressions
+		if valueSpec.Type != nil || len(valueSpec.Values) > 0 {
+			typeName = ""
+			if ident, ok := valueSpec.Type.(*ast.Ident); ok {
+				typeName = ident.Name
+			}
+			exprs = valueSpec.Values
+		}
+
+		for i, name := range valueSpec.Names {
+			if i >= len(exprs) {
+				break
+			}
+			expr := exprs[i]
+
+			// Conversions like Role("admin") also type the constant
+			constType := typeName
+			if call, ok := expr.(*ast.CallExpr); ok && constType == "" && len(call.Args) == 1 {
+				if ident, ok := call.Fun.(*ast.Ident); ok {
+					constType = ident.Name
+				}
+			}
+
+			value := evalConst(expr, iota, values)
+			values[name.Name] = value
+			if constType == "" || name.Name == "_" {
+				continue
+			}
+
+			c := model.Constant{
+				Name: name.Name,
+				Doc:  commentText(valueSpec.Doc),
+				Pos:  p.position(name.Pos()),
+			}
+			if value.Kind() != constant.Unknown {
+				c.Value = value.ExactString()
+			} else {
+				c.Value = p.exprString(expr)
+			}
+			constants[constType] = append(constants[constType], c)
+		}
+	}
+}
+
+// evalConst evaluates a constant expression. It returns an unknown value
+// for expressions it cannot evaluate (e.g., references to other packages).
+func evalConst(expr ast.Expr, iota int, values map[string]constant.Value) constant.Value {
+	unknown := constant.MakeUnknown()
+
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(e.Value, e.Kind, 0)
+
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota))
+		case "true":
+			return constant.MakeBool(true)
+		case "false":
+			return constant.MakeBool(false)
+		}
+		if v, ok := values[e.Name]; ok {
+			return v
+		}
+		return unknown
+
+	case *ast.ParenExpr:
+		return evalConst(e.X, iota, values)
+
+	case *ast.CallExpr:
+		// Type conversion, e.g. Role("admin")
+		if len(e.Args) == 1 {
+			return evalConst(e.Args[0], iota, values)
+		}
+		return unknown
+
+	case *ast.UnaryExpr:
+		x := evalConst(e.X, iota, values)
+		if x.Kind() == constant.Unknown {
+			return unknown
+		}
+		return unaryOp(e.Op, x)
+
+	case *ast.BinaryExpr:
+		x := evalConst(e.X, iota, values)
+		y := evalConst(e.Y, iota, values)
+		if x.Kind() == constant.Unknown || y.Kind() == constant.Unknown {
+			return unknown
+		}
+		return binaryOp(x, e.Op, y)
+	}
+
+	return unknown
+}
+
+// extractTypeDecl extracts the types declared by a type declaration.
+func (p *Parser) extractTypeDecl(genDecl *ast.GenDecl) []model.Type {
+	if genDecl.Tok != token.TYPE {
+		return nil
+	}
+
+	var types []model.Type
+	for _, spec := range genDecl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+		types = append(types, p.extractType(typeSpec, genDecl.Doc))
+	}
+	return types
+}
+
+// extractLocalTypes extracts types declared inside a function body,
+// namespaced by the enclosing function.
+func (p *Parser) extractLocalTypes(fn *ast.FuncDecl) []model.Type {
+	var types []model.Type
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if genDecl, ok := n.(*ast.GenDecl); ok {
+			types = append(types, p.extractTypeDecl(genDecl)...)
 		}
 		return true
 	})
+	if len(types) == 0 {
+		return nil
+	}
 
-	return result, nil
+	scope := fn.Name.Name
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		scope = receiverName(fn.Recv.List[0].Type) + "_" + scope
+	}
+
+	// Rename the types and the references between them
+	names := make(map[string]string, len(types))
+	for _, t := range types {
+		names[t.Name] = scope + "_" + t.Name
+	}
+	for i := range types {
+		types[i].Name = names[types[i].Name]
+		renameFieldRefs(types[i].Fields, names)
+		renameRef(types[i].Underlying, names)
+	}
+	return types
+}
+
+// unaryOp applies a unary operator, returning an unknown value for
+// operands of the wrong kind instead of panicking.
+func unaryOp(op token.Token, x constant.Value) (v constant.Value) {
+	defer func() {
+		if recover() != nil {
+			v = constant.MakeUnknown()
+		}
+	}()
+	return constant.UnaryOp(op, x, 0)
+}
+
+// binaryOp applies a binary operator, returning an unknown value for
+// invalid operations (mismatched kinds, division by zero) instead of panicking.
+func binaryOp(x constant.Value, op token.Token, y constant.Value) (v constant.Value) {
+	defer func() {
+		if recover() != nil {
+			v = constant.MakeUnknown()
+		}
+	}()
+
+	switch op {
+	case token.SHL, token.SHR:
+		shift, ok := constant.Uint64Val(y)
+		if !ok {
+			return constant.MakeUnknown()
+		}
+		return constant.Shift(x, op, uint(shift))
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return constant.MakeBool(constant.Compare(x, op, y))
+	case token.QUO, token.REM:
+		if constant.Sign(y) == 0 {
+			return constant.MakeUnknown()
+		}
+		if op == token.QUO && x.Kind() == constant.Int && y.Kind() == constant.Int {
+			return constant.BinaryOp(x, token.QUO_ASSIGN, y) // Integer division
+		}
+	}
+	return constant.BinaryOp(x, op, y)
+}
+
+// receiverName returns the base type name of a method receiver.
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
+
+// renameFieldRefs renames local type references in a field list.
+func renameFieldRefs(fields []model.Field, names map[string]string) {
+	for i := range fields {
+		renameRef(&fields[i].Type, names)
+		if fields[i].IsEmbedded {
+			if name, ok := names[fields[i].Name]; ok {
+				fields[i].Name = name
+			}
+		}
+	}
+}
+
+// renameRef renames local type references within a TypeRef.
+func renameRef(ref *model.TypeRef, names map[string]string) {
+	if ref == nil {
+		return
+	}
+	if ref.Kind == model.KindBasic {
+		if name, ok := names[ref.Name]; ok {
+			ref.Name = name
+			ref.Raw = name
+		}
+	}
+	renameRef(ref.Elem, names)
+	renameRef(ref.Key, names)
+	renameRef(ref.Value, names)
+	renameFieldRefs(ref.Fields, names)
+
+	// Keep the raw representation in sync with renamed elements
+	switch ref.Kind {
+	case model.KindPointer:
+		ref.Raw = "*" + ref.Elem.Raw
+	case model.KindSlice:
+		ref.Raw = "[]" + ref.Elem.Raw
+	case model.KindArray:
+		ref.Raw = ref.Raw[:strings.Index(ref.Raw, "]")+1] + ref.Elem.Raw
+	case model.KindMap:
+		ref.Raw = fmt.Sprintf("map[%s]%s", ref.Key.Raw, ref.Value.Raw)
+	}
 }
 
 // extractImports extracts import statements from a Go file.
@@ -76,12 +483,61 @@ func (p *Parser) extractImports(file *ast.File) []model.Import {
 	return imports
 }
 
+// importNames maps the local name of each import to its import path.
+func importNames(imports []model.Import) map[string]string {
+	names := make(map[string]string, len(imports))
+	for _, imp := range imports {
+		name := imp.Alias
+		if name == "" {
+			name = PackageName(imp.Path)
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		names[name] = imp.Path
+	}
+	return names
+}
+
+// PackageName guesses the package name for an import path without an alias.
+// It follows the usual conventions: the last path element, ignoring major
+// version suffixes ("/v2"), "gopkg.in" versions (".v3") and "go-"/"go."
+// prefixes.
+func PackageName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && isMajorVersion(name) {
+		name = parts[len(parts)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimPrefix(name, "go.")
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// isMajorVersion reports whether s looks like "v2", "v10", etc.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // extractType extracts type information from an ast.TypeSpec.
 func (p *Parser) extractType(spec *ast.TypeSpec, doc *ast.CommentGroup) model.Type {
 	t := model.Type{
 		Name:       spec.Name.Name,
 		IsExported: ast.IsExported(spec.Name.Name),
 		Doc:        commentText(doc),
+		Pos:        p.position(spec.Name.Pos()),
+		Directives: parseDirectives(doc, spec.Doc),
 	}
 
 	// Determine type kind and extract details
@@ -127,6 +583,10 @@ func (p *Parser) extractFields(fieldList *ast.FieldList) []model.Field {
 		typeRef := p.typeRefFromExpr(f.Type)
 		tag := p.parseTag(f.Tag)
 		doc := commentText(f.Doc)
+		comment := commentText(f.Comment)
+		directives := parseDirectives(f.Doc, f.Comment)
+		p.checkFieldType(f, typeRef, tag)
+		nullable, optional, readonly, writeOnly := fieldFlags(typeRef, tag, directives)
 
 		if len(f.Names) == 0 {
 			// Embedded field
@@ -135,8 +595,15 @@ func (p *Parser) extractFields(fieldList *ast.FieldList) []model.Field {
 				Type:       *typeRef,
 				Tag:        tag,
 				Doc:        doc,
+				Comment:    comment,
 				IsEmbedded: true,
+				Pos:        p.position(f.Type.Pos()),
+				Directives: directives,
 				IsExported: ast.IsExported(typeRef.Name),
+				Nullable:   nullable,
+				Optional:   optional,
+				Readonly:   readonly,
+				WriteOnly:  writeOnly,
 			})
 		} else {
 			for _, name := range f.Names {
@@ -145,7 +612,14 @@ func (p *Parser) extractFields(fieldList *ast.FieldList) []model.Field {
 					Type:       *typeRef,
 					Tag:        tag,
 					Doc:        doc,
+					Comment:    comment,
 					IsExported: ast.IsExported(name.Name),
+					Pos:        p.position(name.Pos()),
+					Directives: directives,
+					Nullable:   nullable,
+					Optional:   optional,
+					Readonly:   readonly,
+					WriteOnly:  writeOnly,
 				})
 			}
 		}
@@ -153,6 +627,54 @@ func (p *Parser) extractFields(fieldList *ast.FieldList) []model.Field {
 	return fields
 }
 
+// fieldFlags tells whether a field is nullable (a pointer), optional (the
+// json tag has omitempty or omitzero), readonly and write-only. The
+// //gogen:nullable, //gogen:optional, //gogen:readonly and
+// //gogen:writeonly directives set the flags for fields whose type or tag
+// does not.
+func fieldFlags(ref *model.TypeRef, tag model.StructTag, directives model.Directives) (nullable, optional, readonly, writeOnly bool) {
+	nullable = ref.Kind == model.KindPointer || directives.Has("nullable")
+	optional = tag.HasOption("json", "omitempty") || tag.HasOption("json", "omitzero") || directives.Has("optional")
+	readonly = directives.Has("readonly")
+	writeOnly = directives.Has("writeonly")
+	return nullable, optional, readonly, writeOnly
+}
+
+// checkFieldType warns about exported fields whose type cannot be
+// represented in generated code. Fields skipped with a "-" name for the
+// tag key are fine; other tag keys (e.g., db:"-") do not skip them.
+func (p *Parser) checkFieldType(f *ast.Field, ref *model.TypeRef, tag model.StructTag) {
+	if p.diags == nil || tag.Options[p.tagKey].Name == "-" {
+		return
+	}
+	if !isUnsupported(ref) {
+		return
+	}
+	for _, name := range f.Names {
+		if ast.IsExported(name.Name) {
+			p.diags.Warnf(p.position(name.Pos()), diag.CodeUnsupportedType, "field %s has unsupported type %s", name.Name, ref.Raw)
+		}
+	}
+}
+
+// isUnsupported reports whether a type reference is or contains a chan or
+// func type.
+func isUnsupported(ref *model.TypeRef) bool {
+	if ref == nil {
+		return false
+	}
+	switch ref.Kind {
+	case model.KindChan, model.KindFunc:
+		return true
+	case model.KindMap:
+		return isUnsupported(ref.Key) || isUnsupported(ref.Value)
+	case model.KindStruct:
+		// Fields of inline structs are checked on their own
+		return false
+	}
+	return isUnsupported(ref.Elem)
+}
+
 // typeRefFromExpr converts an ast.Expr to a TypeRef.
 func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 	switch t := expr.(type) {
@@ -169,10 +691,15 @@ func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 		if ident, ok := t.X.(*ast.Ident); ok {
 			pkg = ident.Name
 		}
+		pkgPath := p.imports[pkg]
+		if pkgPath == "" {
+			pkgPath = pkg
+		}
 		return &model.TypeRef{
 			Kind:    model.KindNamed,
 			Name:    t.Sel.Name,
 			Package: pkg,
+			PkgPath: pkgPath,
 			Raw:     fmt.Sprintf("%s.%s", pkg, t.Sel.Name),
 		}
 
@@ -195,11 +722,17 @@ func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 			}
 		}
 		// Array
-		return &model.TypeRef{
+		ref := &model.TypeRef{
 			Kind: model.KindArray,
 			Elem: elem,
-			Raw:  fmt.Sprintf("[...]%s", elem.Raw),
+			Raw:  fmt.Sprintf("[%s]%s", p.exprString(t.Len), elem.Raw),
 		}
+		if lit, ok := t.Len.(*ast.BasicLit); ok && lit.Kind == token.INT {
+			if n, err := strconv.ParseInt(lit.Value, 0, 0); err == nil {
+				ref.Len = int(n)
+			}
+		}
+		return ref
 
 	case *ast.MapType:
 		key := p.typeRefFromExpr(t.Key)
@@ -211,6 +744,14 @@ func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 			Raw:   fmt.Sprintf("map[%s]%s", key.Raw, value.Raw),
 		}
 
+	case *ast.StructType:
+		// Anonymous struct, fields are kept inline
+		return &model.TypeRef{
+			Kind:   model.KindStruct,
+			Fields: p.extractFields(t.Fields),
+			Raw:    p.exprString(t),
+		}
+
 	case *ast.InterfaceType:
 		return &model.TypeRef{
 			Kind: model.KindInterface,
@@ -220,18 +761,25 @@ func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 
 	case *ast.ChanType:
 		elem := p.typeRefFromExpr(t.Value)
+		prefix := "chan "
+		switch t.Dir {
+		case ast.SEND:
+			prefix = "chan<- "
+		case ast.RECV:
+			prefix = "<-chan "
+		}
 		return &model.TypeRef{
-			Kind: model.KindBasic,
+			Kind: model.KindChan,
 			Name: "chan",
 			Elem: elem,
-			Raw:  "chan " + elem.Raw,
+			Raw:  prefix + elem.Raw,
 		}
 
 	case *ast.FuncType:
 		return &model.TypeRef{
-			Kind: model.KindBasic,
+			Kind: model.KindFunc,
 			Name: "func",
-			Raw:  "func",
+			Raw:  p.exprString(t),
 		}
 
 	case *ast.Ellipsis:
@@ -244,6 +792,7 @@ func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 		}
 
 	default:
+		p.diags.Warnf(p.position(expr.Pos()), diag.CodeUnknownType, "unsupported type expression %s, treated as unknown", p.exprString(expr))
 		return &model.TypeRef{
 			Kind: model.KindBasic,
 			Name: "unknown",
@@ -252,27 +801,142 @@ func (p *Parser) typeRefFromExpr(expr ast.Expr) *model.TypeRef {
 	}
 }
 
-// parseTag parses a struct tag.
+// position converts a token.Pos into a model.Position.
+func (p *Parser) position(pos token.Pos) model.Position {
+	position := p.fset.Position(pos)
+	return model.Position{
+		File:   position.Filename,
+		Line:   position.Line,
+		Column: position.Column,
+	}
+}
+
+// exprString renders an expression back to Go source.
+func (p *Parser) exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, p.fset, expr); err != nil {
+		return "unknown"
+	}
+	return buf.String()
+}
+
+// parseTag parses a struct tag, keeping every key.
 func (p *Parser) parseTag(lit *ast.BasicLit) model.StructTag {
+	tag := model.StructTag{
+		Values:  make(map[string]string),
+		Options: make(map[string]model.TagValue),
+	}
 	if lit == nil {
-		return model.StructTag{Values: make(map[string]string)}
+		return tag
 	}
 
-	raw := strings.Trim(lit.Value, "`")
-	tag := reflect.StructTag(raw)
+	raw := lit.Value
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		raw = unquoted
+	}
+	tag.Raw = raw
 
-	// Parse common tag keys
-	values := make(map[string]string)
-	for _, key := range []string{"json", "yaml", "xml", "db", "form", "validate", "binding", "bson"} {
-		if v, ok := tag.Lookup(key); ok {
-			values[key] = v
+	for _, kv := range splitTag(raw) {
+		if _, dup := tag.Values[kv[0]]; dup {
+			// Like reflect.StructTag.Lookup, the first occurrence wins
+			continue
 		}
+		tag.Keys = append(tag.Keys, kv[0])
+		tag.Values[kv[0]] = kv[1]
+		tag.Options[kv[0]] = parseTagValue(kv[1])
 	}
 
-	return model.StructTag{
-		Raw:    raw,
-		Values: values,
+	return tag
+}
+
+// splitTag splits a raw struct tag into key/value pairs, following the
+// conventional `key:"value" key2:"value2"` format used by reflect.StructTag.
+func splitTag(tag string) [][2]string {
+	var pairs [][2]string
+	for tag != "" {
+		// Skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			break
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs
+}
+
+// parseTagValue splits a tag value into its name and options.
+func parseTagValue(value string) model.TagValue {
+	parts := strings.Split(value, ",")
+	v := model.TagValue{Name: strings.TrimSpace(parts[0])}
+	for _, opt := range parts[1:] {
+		if opt = strings.TrimSpace(opt); opt != "" {
+			v.Options = append(v.Options, opt)
+		}
+	}
+	return v
+}
+
+// directivePrefix introduces a gogen directive comment.
+const directivePrefix = "//gogen:"
+
+// parseDirectives collects "//gogen:name args" directives from comment
+// groups. Like other Go directives they have no space after the slashes,
+// so they are not part of the doc text.
+func parseDirectives(groups ...*ast.CommentGroup) model.Directives {
+	var directives model.Directives
+	for _, cg := range groups {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, directivePrefix) {
+				continue
+			}
+			name, args, _ := strings.Cut(strings.TrimPrefix(c.Text, directivePrefix), " ")
+			if name == "" {
+				continue
+			}
+			if directives == nil {
+				directives = make(model.Directives)
+			}
+			directives[name] = strings.TrimSpace(args)
+		}
 	}
+	return directives
 }
 
 // commentText extracts text from a comment group.
@@ -0,0 +1,70 @@
+// Package templates embeds the built-in gogen templates.
+package templates
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// FS holds the built-in templates.
+//
+//go:embed *.tmpl
+var FS embed.FS
+
+// Names returns the names of the built-in templates (without extension).
+func Names() []string {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmpl") {
+			names = append(names, strings.TrimSuffix(e.Name(), ".tmpl"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the file name of a built-in template, accepting the name
+// with or without the ".tmpl" extension.
+func Lookup(name string) (string, bool) {
+	file := strings.TrimSuffix(name, ".tmpl") + ".tmpl"
+	if _, err := fs.Stat(FS, file); err != nil {
+		return "", false
+	}
+	return file, true
+}
+
+// outputExts holds the extension of the files generated by the built-in
+// templates whose name does not start with "go-".
+var outputExts = map[string]string{
+	"typescript":   ".ts",
+	"zod":          ".ts",
+	"valibot":      ".ts",
+	"valibot-form": ".ts",
+	"pydantic":     ".py",
+	"rust":         ".rs",
+	"kotlin":       ".kt",
+	"swift":        ".swift",
+	"proto":        ".proto",
+	"graphql":      ".graphql",
+	"sql-postgres": ".sql",
+	"sql-sqlite":   ".sql",
+}
+
+// OutputExt returns the extension of the files a built-in template
+// generates, or "" if name is not a built-in template.
+func OutputExt(name string) string {
+	name = strings.TrimSuffix(name, ".tmpl")
+	if _, ok := Lookup(name); !ok {
+		return ""
+	}
+	if strings.HasPrefix(name, "go-") {
+		return ".go"
+	}
+	return outputExts[name]
+}